@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -14,12 +15,10 @@ import (
 	"github.com/GetStream/tikv-ui/pkg/server"
 	"github.com/GetStream/tikv-ui/pkg/services"
 	"github.com/GetStream/tikv-ui/pkg/utils"
-	"github.com/tikv/client-go/v2/config"
-	"github.com/tikv/client-go/v2/rawkv"
 )
 
 func main() {
-	// Read PD addresses from env: TIKV_PD_ADDRS="127.0.0.1:2379|My Cluster 1,127.0.0.1:2381;server-2.com:2379|Cluster 2"
+	// Read PD addresses from env: TIKV_PD_ADDRS="127.0.0.1:2379|My Cluster 1,127.0.0.1:2381;server-2.com:2379|Cluster 2|txn"
 	pdAddrsEnv := os.Getenv("TIKV_PD_ADDRS")
 	if pdAddrsEnv == "" {
 		log.Fatal("TIKV_PD_ADDRS env var is required (comma-separated PD addresses)")
@@ -32,19 +31,45 @@ func main() {
 	}
 	ctx := context.Background()
 	cache := utils.NewCache()
-	// Create TiKV RawKV client for default cluster
-	cli, err := rawkv.NewClient(ctx, clusters[0].PDAddrs, config.DefaultConfig().Security)
+	// Create TiKV client for default cluster
+	conn, err := server.Connect(ctx, clusters[0])
 	if err != nil {
-		log.Fatalf("failed to create TiKV RawKV client: %v", err)
+		log.Fatalf("failed to create TiKV client: %v", err)
 	}
 
-	log.Printf("Connected to default TiKV cluster ID: %d", cli.ClusterID())
+	log.Printf("Connected to default TiKV cluster ID: %d (%s mode)", conn.ClusterID, conn.Mode)
 
-	srv := server.New(cli, clusters[0].PDAddrs, clusters[0].Name, cache)
+	srv := server.New(conn, cache)
 	defer srv.Close()
 
+	if path := os.Getenv("TIKV_UI_KEY_LAYOUTS_FILE"); path != "" {
+		layouts, err := utils.LoadKeyLayouts(path)
+		if err != nil {
+			log.Fatalf("failed to load key layouts: %v", err)
+		}
+		srv.KeyLayouts = layouts
+	}
+
+	if path := os.Getenv("TIKV_UI_AUDIT_FILE"); path != "" {
+		maxSize := getIntEnv("TIKV_UI_AUDIT_MAX_SIZE_MB", 100)
+		audit, err := services.NewAuditLog(path, int64(maxSize)<<20, getIntEnv("TIKV_UI_AUDIT_MAX_FILES", 10))
+		if err != nil {
+			log.Fatalf("failed to open audit log: %v", err)
+		}
+		srv.Audit = audit
+		log.Printf("Audit log: %s", path)
+	}
+
+	if size := getIntEnv("TIKV_UI_HISTORY_SIZE", 500); size > 0 {
+		srv.History = utils.NewHistory(size)
+	}
+
+	tasks := services.NewTaskManager()
+
 	for _, cluster := range clusters[1:] {
-		srv.AddCluster(ctx, cluster.Name, cluster.PDAddrs)
+		if _, err := srv.AddCluster(ctx, cluster); err != nil {
+			log.Printf("failed to connect to cluster %s: %v", cluster.Name, err)
+		}
 	}
 
 	// Start metrics monitor for all clusters
@@ -72,11 +97,37 @@ func main() {
 	mux.HandleFunc("/api/clusters/connect", handlers.Connect(srv))
 	mux.HandleFunc("/api/clusters/switch", handlers.SwitchCluster(srv))
 
+	// Key layouts
+	mux.HandleFunc("/api/key-layouts", handlers.KeyLayouts(srv))
+
 	// Raw KV operations
 	mux.HandleFunc("/api/raw/get", handlers.Get(srv))
 	mux.HandleFunc("/api/raw/put", handlers.Put(srv))
+	mux.HandleFunc("/api/raw/patch", handlers.Patch(srv))
 	mux.HandleFunc("/api/raw/delete", handlers.Delete(srv))
+	mux.HandleFunc("/api/raw/ttl", handlers.SetTTL(srv))
+	mux.HandleFunc("/api/raw/cas", handlers.CompareAndSwap(srv))
 	mux.HandleFunc("/api/raw/scan", handlers.Scan(srv))
+	mux.HandleFunc("/api/raw/batch-get", handlers.BatchGet(srv))
+	mux.HandleFunc("/api/raw/batch-put", handlers.BatchPut(srv))
+	mux.HandleFunc("/api/raw/batch-delete", handlers.BatchDelete(srv))
+	mux.HandleFunc("/api/raw/delete-range", handlers.DeleteRange(srv))
+	mux.HandleFunc("/api/raw/export", handlers.Export(srv))
+	mux.HandleFunc("/api/raw/import", handlers.Import(srv))
+	mux.HandleFunc("/api/raw/count", handlers.Count(srv))
+
+	// Cross-cluster operations
+	mux.HandleFunc("/api/clusters/diff", handlers.Diff(srv))
+	mux.HandleFunc("/api/clusters/copy", handlers.CopyRange(srv, tasks))
+
+	// Background tasks
+	mux.HandleFunc("/api/tasks", handlers.ListTasks(tasks))
+	mux.HandleFunc("/api/tasks/cancel", handlers.CancelTask(tasks))
+
+	// Audit log and write history
+	mux.HandleFunc("/api/audit", handlers.AuditLog(srv))
+	mux.HandleFunc("/api/history", handlers.History(srv))
+	mux.HandleFunc("/api/history/revert", handlers.Revert(srv))
 
 	// Metrics
 	mux.HandleFunc("/api/metrics", handlers.Metrics(srv))
@@ -115,9 +166,17 @@ func main() {
 	if port == "" {
 		port = "8081"
 	}
+	var handler http.Handler = server.LoggingMiddleware(mux)
+	if providers := authProviders(); len(providers) > 0 {
+		handler = server.NewAuth(providers...).Middleware(handler)
+	} else {
+		log.Println("WARNING: no authentication configured, the API is open to anyone who can reach it")
+	}
+	corsOrigins := utils.SplitAndTrim(os.Getenv("TIKV_UI_CORS_ORIGINS"), ",")
+
 	httpServer := &http.Server{
 		Addr:         ":" + port,
-		Handler:      server.CORSMiddleware(server.LoggingMiddleware(mux)),
+		Handler:      server.CORSMiddleware(corsOrigins, handler),
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
@@ -145,6 +204,42 @@ func main() {
 	}
 }
 
+// authProviders builds the authentication providers configured through the environment.
+func authProviders() []server.AuthProvider {
+	var providers []server.AuthProvider
+	if spec := os.Getenv("TIKV_UI_AUTH_TOKENS"); spec != "" {
+		tokens, err := server.NewTokenAuth(spec)
+		if err != nil {
+			log.Fatalf("invalid TIKV_UI_AUTH_TOKENS: %v", err)
+		}
+		providers = append(providers, tokens)
+		log.Println("Authentication: static API tokens")
+	}
+	if path := os.Getenv("TIKV_UI_AUTH_USERS_FILE"); path != "" {
+		users, err := server.LoadBasicAuth(path)
+		if err != nil {
+			log.Fatalf("failed to load users file: %v", err)
+		}
+		providers = append(providers, users)
+		log.Printf("Authentication: basic auth users from %s", path)
+	}
+	issuer, jwksURL := os.Getenv("TIKV_UI_OIDC_ISSUER"), os.Getenv("TIKV_UI_OIDC_JWKS_URL")
+	if issuer != "" || jwksURL != "" {
+		oidc, err := server.NewOIDCAuth(server.OIDCConfig{
+			Issuer:        issuer,
+			Audience:      os.Getenv("TIKV_UI_OIDC_AUDIENCE"),
+			JWKSURL:       jwksURL,
+			IdentityClaim: os.Getenv("TIKV_UI_OIDC_IDENTITY_CLAIM"),
+		})
+		if err != nil {
+			log.Fatalf("invalid OIDC configuration: %v", err)
+		}
+		providers = append(providers, oidc)
+		log.Printf("Authentication: OIDC tokens from %s", issuer)
+	}
+	return providers
+}
+
 func getDurationEnv(key string, fallback time.Duration) time.Duration {
 	value := strings.TrimSpace(os.Getenv(key))
 	if value == "" {
@@ -159,3 +254,18 @@ func getDurationEnv(key string, fallback time.Duration) time.Duration {
 
 	return duration
 }
+
+func getIntEnv(key string, fallback int) int {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("invalid %s=%q, using default %d", key, value, fallback)
+		return fallback
+	}
+
+	return n
+}
@@ -5,6 +5,7 @@ go 1.25.1
 require (
 	github.com/tikv/client-go/v2 v2.0.7
 	github.com/vmihailenco/msgpack/v5 v5.4.1
+	golang.org/x/crypto v0.43.0
 )
 
 require (
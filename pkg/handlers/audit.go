@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/GetStream/tikv-ui/pkg/server"
+	"github.com/GetStream/tikv-ui/pkg/services"
+	"github.com/GetStream/tikv-ui/pkg/types"
+	"github.com/GetStream/tikv-ui/pkg/utils"
+)
+
+// Audit operations.
+const (
+	auditPut         = "put"
+	auditPatch       = "patch"
+	auditCAS         = "cas"
+	auditTTL         = "ttl"
+	auditDelete      = "delete"
+	auditBatchPut    = "batch-put"
+	auditBatchDelete = "batch-delete"
+	auditDeleteRange = "delete-range"
+	auditImport      = "import"
+	auditCopy        = "copy"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// recordAudit completes rec with the caller and time and appends it to the audit log. The
+// mutation has already happened, so a failure to record it is logged rather than returned.
+func recordAudit(r *http.Request, s *server.Server, rec types.AuditRecord) {
+	callerOf(r).record(s, rec)
+}
+
+// auditCaller identifies who made a request, captured so that records can still be written after
+// the request has been answered, e.g. by background tasks.
+type auditCaller struct {
+	identity     string
+	clientIP     string
+	forwardedFor string
+}
+
+func callerOf(r *http.Request) auditCaller {
+	return auditCaller{
+		identity:     server.CallerIdentity(r.Context()),
+		clientIP:     server.ClientIP(r),
+		forwardedFor: r.Header.Get("X-Forwarded-For"),
+	}
+}
+
+func (c auditCaller) record(s *server.Server, rec types.AuditRecord) {
+	if !s.Audit.Enabled() {
+		return
+	}
+	rec.Time = time.Now().UTC()
+	rec.Identity = c.identity
+	rec.ClientIP = c.clientIP
+	rec.ForwardedFor = c.forwardedFor
+	if err := s.Audit.Record(rec); err != nil {
+		log.Printf("audit: failed to record %s on %s: %v", rec.Operation, rec.Cluster, err)
+	}
+}
+
+// batchAuditFunc records one entry per key of a batch mutation; values is nil for deletes and
+// previous is nil when the previous values could not be read. A failed batch may still have been
+// partially applied, so its keys are recorded too, with the error.
+type batchAuditFunc func(keys, previous, values [][]byte, batchErr error)
+
+// batchAuditor returns the batchAuditFunc of a bulk mutation on cluster, adding details to every
+// entry, or nil when auditing is disabled.
+func (c auditCaller) batchAuditor(s *server.Server, cluster, operation string, details map[string]any) batchAuditFunc {
+	if !s.Audit.Enabled() {
+		return nil
+	}
+	return func(keys, previous, values [][]byte, batchErr error) {
+		for i, key := range keys {
+			rec := types.AuditRecord{Cluster: cluster, Operation: operation, Key: key}
+			if previous != nil {
+				rec.PreviousValue = previous[i]
+			}
+			if values != nil {
+				rec.NewValue = values[i]
+			}
+			if len(details) > 0 || batchErr != nil {
+				rec.Details = make(map[string]any, len(details)+1)
+				for k, v := range details {
+					rec.Details[k] = v
+				}
+				if batchErr != nil {
+					rec.Details["error"] = batchErr.Error()
+				}
+			}
+			c.record(s, rec)
+		}
+	}
+}
+
+// recordBatchAudit records the keys of a batch request with batchAuditor.
+func recordBatchAudit(r *http.Request, s *server.Server, cluster, operation string, keys, previous, values [][]byte, batchErr error) {
+	if record := callerOf(r).batchAuditor(s, cluster, operation, nil); record != nil {
+		record(keys, previous, values, batchErr)
+	}
+}
+
+// auditDetails returns the details of a single-key mutation: the column family when it is not
+// the default one.
+func auditDetails(cf string) map[string]any {
+	if cf == "" || cf == utils.CFDefault {
+		return nil
+	}
+	return map[string]any{"cf": cf}
+}
+
+// AuditLog handles requests to query the audit log. Filters are passed as query parameters:
+// cluster, operation, identity, key (with key_encoding), since and until (RFC 3339) and limit.
+func AuditLog(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			utils.MethodNotAllowed(w)
+			return
+		}
+		if !s.Audit.Enabled() {
+			utils.WriteError(w, http.StatusNotFound, "audit log is not enabled")
+			return
+		}
+
+		q := r.URL.Query()
+		enc := types.Encoding{KeyEncoding: q.Get("key_encoding"), ValueEncoding: q.Get("value_encoding")}
+		if !validateEncoding(w, enc) {
+			return
+		}
+		query := services.AuditQuery{
+			Cluster:   q.Get("cluster"),
+			Operation: q.Get("operation"),
+			Identity:  q.Get("identity"),
+			Limit:     defaultAuditLimit,
+		}
+		if k := q.Get("key"); k != "" {
+			key, ok := decodeField(w, "key", k, enc.KeyEncoding)
+			if !ok {
+				return
+			}
+			query.Key = key
+		}
+		for _, p := range []struct {
+			name string
+			dst  *time.Time
+		}{{"since", &query.Since}, {"until", &query.Until}} {
+			if v := q.Get(p.name); v != "" {
+				t, err := time.Parse(time.RFC3339, v)
+				if err != nil {
+					utils.WriteError(w, http.StatusBadRequest, p.name+" must be an RFC 3339 time")
+					return
+				}
+				*p.dst = t
+			}
+		}
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 || n > maxAuditLimit {
+				utils.WriteError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxAuditLimit))
+				return
+			}
+			query.Limit = n
+		}
+
+		records, err := s.Audit.Query(query)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "failed to read audit log: "+err.Error())
+			return
+		}
+
+		entries := make([]types.AuditEntry, len(records))
+		for i, rec := range records {
+			entries[i] = types.AuditEntry{
+				Time:          rec.Time,
+				Cluster:       rec.Cluster,
+				Operation:     rec.Operation,
+				Key:           utils.EncodeBytes(rec.Key, enc.KeyEncoding),
+				PreviousValue: formatAuditValue(rec.PreviousValue, enc.ValueEncoding),
+				NewValue:      formatAuditValue(rec.NewValue, enc.ValueEncoding),
+				Identity:      rec.Identity,
+				ClientIP:      rec.ClientIP,
+				ForwardedFor:  rec.ForwardedFor,
+				Details:       rec.Details,
+			}
+		}
+		utils.WriteJSON(w, http.StatusOK, types.AuditResponse{Entries: entries})
+	}
+}
+
+func formatAuditValue(v []byte, enc string) *string {
+	if v == nil {
+		return nil
+	}
+	formatted := utils.FormatValue(v, enc)
+	return &formatted
+}
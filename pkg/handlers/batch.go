@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/GetStream/tikv-ui/pkg/server"
+	"github.com/GetStream/tikv-ui/pkg/types"
+	"github.com/GetStream/tikv-ui/pkg/utils"
+	"github.com/tikv/client-go/v2/rawkv"
+)
+
+// maxBatchSize caps the number of keys accepted by a single batch request.
+const maxBatchSize = 1000
+
+// BatchGet handles requests to retrieve several keys in one round trip
+func BatchGet(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			utils.MethodNotAllowed(w)
+			return
+		}
+
+		var req types.BatchGetRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		if !validateBatchSize(w, len(req.Keys)) || !validateEncoding(w, req.Encoding) {
+			return
+		}
+
+		items := make([]types.BatchGetItem, len(req.Keys))
+		keys := make([][]byte, 0, len(req.Keys))
+		positions := make([]int, 0, len(req.Keys))
+		for i, k := range req.Keys {
+			key, err := decodeBatchKey(k, req.KeyEncoding)
+			if err != nil {
+				items[i] = types.BatchGetItem{GetResponse: types.GetResponse{Key: k}, Error: err.Error()}
+				continue
+			}
+			keys = append(keys, key)
+			positions = append(positions, i)
+		}
+
+		cli, ok := activeRawClient(w, s)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		if len(keys) > 0 {
+			values, err := cli.BatchGet(ctx, keys)
+			for j, key := range keys {
+				if err != nil {
+					items[positions[j]] = types.BatchGetItem{
+						GetResponse: newGetResponse(key, nil, req.Encoding, s.KeyLayouts),
+						Error:       "TiKV BatchGet error: " + err.Error(),
+					}
+					continue
+				}
+				items[positions[j]] = types.BatchGetItem{GetResponse: newGetResponse(key, values[j], req.Encoding, s.KeyLayouts)}
+			}
+		}
+
+		utils.WriteJSON(w, http.StatusOK, types.BatchGetResponse{Items: items})
+	}
+}
+
+// BatchPut handles requests to store several key-value pairs in one round trip
+func BatchPut(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			utils.MethodNotAllowed(w)
+			return
+		}
+
+		var req types.BatchPutRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		if !validateBatchSize(w, len(req.Items)) || !validateEncoding(w, req.Encoding) {
+			return
+		}
+
+		results := make([]types.BatchResult, len(req.Items))
+		keys := make([][]byte, 0, len(req.Items))
+		values := make([][]byte, 0, len(req.Items))
+		positions := make([]int, 0, len(req.Items))
+		for i, item := range req.Items {
+			results[i].Key = item.Key
+			key, err := decodeBatchKey(item.Key, req.KeyEncoding)
+			if err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			value, err := utils.DecodeBytes(item.Value, req.ValueEncoding)
+			if err != nil {
+				results[i].Error = "invalid value: " + err.Error()
+				continue
+			}
+			keys = append(keys, key)
+			values = append(values, value)
+			positions = append(positions, i)
+		}
+
+		conn, ok := activeRawConnection(w, s)
+		if !ok || !checkWritable(w, conn) {
+			return
+		}
+		cli := conn.Client
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		if len(keys) > 0 {
+			previous := auditPrevious(ctx, s, cli, keys)
+			err := cli.BatchPut(ctx, keys, values)
+			applyBatchResult(results, positions, "TiKV BatchPut error", err)
+			recordBatchAudit(r, s, conn.Name, auditBatchPut, keys, previous, values, err)
+		}
+
+		utils.WriteJSON(w, http.StatusOK, newBatchResponse(results))
+	}
+}
+
+// BatchDelete handles requests to remove several keys in one round trip
+func BatchDelete(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			utils.MethodNotAllowed(w)
+			return
+		}
+
+		var req types.BatchDeleteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		if !validateBatchSize(w, len(req.Keys)) || !validateEncoding(w, req.Encoding) {
+			return
+		}
+
+		results := make([]types.BatchResult, len(req.Keys))
+		keys := make([][]byte, 0, len(req.Keys))
+		positions := make([]int, 0, len(req.Keys))
+		for i, k := range req.Keys {
+			results[i].Key = k
+			key, err := decodeBatchKey(k, req.KeyEncoding)
+			if err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			keys = append(keys, key)
+			positions = append(positions, i)
+		}
+
+		conn, ok := activeRawConnection(w, s)
+		if !ok || !checkWritable(w, conn) {
+			return
+		}
+		cli := conn.Client
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		if len(keys) > 0 {
+			previous := auditPrevious(ctx, s, cli, keys)
+			err := cli.BatchDelete(ctx, keys)
+			applyBatchResult(results, positions, "TiKV BatchDelete error", err)
+			recordBatchAudit(r, s, conn.Name, auditBatchDelete, keys, previous, nil, err)
+		}
+
+		utils.WriteJSON(w, http.StatusOK, newBatchResponse(results))
+	}
+}
+
+// auditPrevious reads the values a batch is about to overwrite when auditing is enabled. The read
+// is best effort: if it fails the batch goes ahead and is recorded without previous values.
+func auditPrevious(ctx context.Context, s *server.Server, cli *rawkv.Client, keys [][]byte, opts ...rawkv.RawOption) [][]byte {
+	if !s.Audit.Enabled() {
+		return nil
+	}
+	previous, err := cli.BatchGet(ctx, keys, opts...)
+	if err != nil {
+		log.Printf("audit: failed to read previous values: %v", err)
+		return nil
+	}
+	return previous
+}
+
+// validateBatchSize rejects empty and oversized batches, writing a 400 response on failure.
+func validateBatchSize(w http.ResponseWriter, n int) bool {
+	if n == 0 {
+		utils.WriteError(w, http.StatusBadRequest, "batch must not be empty")
+		return false
+	}
+	if n > maxBatchSize {
+		utils.WriteError(w, http.StatusBadRequest, "batch size must not exceed "+strconv.Itoa(maxBatchSize))
+		return false
+	}
+	return true
+}
+
+// decodeBatchKey decodes a single key of a batch request.
+func decodeBatchKey(k, enc string) ([]byte, error) {
+	if k == "" {
+		return nil, errors.New("key is required")
+	}
+	key, err := utils.DecodeBytes(k, enc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	return key, nil
+}
+
+// applyBatchResult marks every submitted key as succeeded or failed with the outcome of the TiKV call.
+// TiKV splits batches by region, so a failed call may have been partially applied.
+func applyBatchResult(results []types.BatchResult, positions []int, prefix string, err error) {
+	for _, i := range positions {
+		if err != nil {
+			results[i].Error = prefix + ": " + err.Error()
+			continue
+		}
+		results[i].OK = true
+	}
+}
+
+func newBatchResponse(results []types.BatchResult) types.BatchResponse {
+	resp := types.BatchResponse{Results: results}
+	for _, res := range results {
+		if res.OK {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+	}
+	return resp
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/GetStream/tikv-ui/pkg/server"
+	"github.com/GetStream/tikv-ui/pkg/types"
+	"github.com/GetStream/tikv-ui/pkg/utils"
+)
+
+// CompareAndSwap handles atomic compare-and-swap requests. A failed comparison is reported with
+// 409 and the current value, so the caller can retry against it.
+func CompareAndSwap(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			utils.MethodNotAllowed(w)
+			return
+		}
+
+		var req types.CASRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		if req.Key == "" {
+			utils.WriteError(w, http.StatusBadRequest, "key is required")
+			return
+		}
+		if !validateEncoding(w, req.Encoding) {
+			return
+		}
+		key, ok := decodeField(w, "key", req.Key, req.KeyEncoding)
+		if !ok {
+			return
+		}
+		value, ok := decodeField(w, "value", req.Value, req.ValueEncoding)
+		if !ok {
+			return
+		}
+		var previous []byte
+		if req.PreviousValue != nil {
+			if previous, ok = decodeField(w, "previous_value", *req.PreviousValue, req.ValueEncoding); !ok {
+				return
+			}
+		}
+
+		conn, ok := activeRawConnection(w, s)
+		if !ok || !checkWritable(w, conn) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		current, swapped, err := conn.Client.CompareAndSwap(ctx, key, previous, value)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "TiKV CompareAndSwap error: "+err.Error())
+			return
+		}
+
+		resp := types.CASResponse{Swapped: swapped}
+		if current != nil {
+			formatted := utils.FormatValue(current, req.ValueEncoding)
+			resp.PreviousValue = &formatted
+		}
+		if !swapped {
+			if current != nil {
+				resp.ETag = utils.ETag(current)
+			}
+			utils.WriteJSON(w, http.StatusConflict, resp)
+			return
+		}
+
+		recordAudit(r, s, types.AuditRecord{Cluster: conn.Name, Operation: auditCAS, Key: key, PreviousValue: current, NewValue: value})
+
+		resp.ETag = utils.ETag(value)
+		w.Header().Set("ETag", `"`+resp.ETag+`"`)
+		utils.WriteJSON(w, http.StatusOK, resp)
+	}
+}
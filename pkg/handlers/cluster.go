@@ -28,6 +28,10 @@ func Connect(s *server.Server) http.HandlerFunc {
 			utils.WriteError(w, http.StatusBadRequest, "pd_addrs is required")
 			return
 		}
+		if req.Mode != "" && req.Mode != types.ClusterModeRaw && req.Mode != types.ClusterModeTxn {
+			utils.WriteError(w, http.StatusBadRequest, "mode must be raw or txn")
+			return
+		}
 		if req.Name == "" {
 			req.Name = "cluster-" + time.Now().Format("20060102-150405")
 		}
@@ -35,7 +39,7 @@ func Connect(s *server.Server) http.HandlerFunc {
 		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 		defer cancel()
 
-		conn, err := s.AddCluster(ctx, req.Name, req.PDAddrs)
+		conn, err := s.AddCluster(ctx, types.Cluster{Name: req.Name, PDAddrs: req.PDAddrs, Mode: req.Mode, ReadOnly: req.ReadOnly})
 		if err != nil {
 			utils.WriteError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -51,11 +55,23 @@ func Connect(s *server.Server) http.HandlerFunc {
 			Name:      conn.Name,
 			ClusterID: conn.ClusterID,
 			PDAddrs:   conn.PDAddrs,
+			Mode:      conn.Mode,
+			ReadOnly:  conn.ReadOnly,
 			Active:    true,
 		})
 	}
 }
 
+// checkWritable rejects writes to read-only clusters, writing a 403 response. Every handler that
+// mutates a cluster calls it before touching TiKV.
+func checkWritable(w http.ResponseWriter, conn *server.ClusterConnection) bool {
+	if conn.ReadOnly {
+		utils.WriteError(w, http.StatusForbidden, "cluster "+conn.Name+" is read-only")
+		return false
+	}
+	return true
+}
+
 // ListClusters handles requests to list all connected clusters
 func ListClusters(s *server.Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -73,6 +89,8 @@ func ListClusters(s *server.Server) http.HandlerFunc {
 				Name:      conn.Name,
 				ClusterID: conn.ClusterID,
 				PDAddrs:   conn.PDAddrs,
+				Mode:      conn.Mode,
+				ReadOnly:  conn.ReadOnly,
 				Active:    conn.Name == activeCluster,
 			})
 		}
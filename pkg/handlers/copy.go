@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/GetStream/tikv-ui/pkg/server"
+	"github.com/GetStream/tikv-ui/pkg/services"
+	"github.com/GetStream/tikv-ui/pkg/types"
+	"github.com/GetStream/tikv-ui/pkg/utils"
+	"github.com/tikv/client-go/v2/rawkv"
+)
+
+const (
+	defaultCopyBatch = 256
+	// copyIOTimeout bounds each scan and write issued by a copy task.
+	copyIOTimeout = 30 * time.Second
+)
+
+// CopyRange handles requests to copy a key range from one registered cluster to another.
+// The copy runs as a background task; the response carries the task to poll or cancel.
+func CopyRange(s *server.Server, tasks *services.TaskManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			utils.MethodNotAllowed(w)
+			return
+		}
+
+		var req types.CopyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		if req.Source == "" || req.Target == "" {
+			utils.WriteError(w, http.StatusBadRequest, "source and target are required")
+			return
+		}
+		source, ok := rawCluster(w, s, req.Source)
+		if !ok {
+			return
+		}
+		target, ok := rawCluster(w, s, req.Target)
+		if !ok || (!req.DryRun && !checkWritable(w, target)) {
+			return
+		}
+		if !validateEncoding(w, req.Encoding) {
+			return
+		}
+		startKey, endKey, ok := decodeRange(w, s.KeyLayouts, req.KeyRange, req.KeyEncoding)
+		if !ok {
+			return
+		}
+		rewriteFrom, ok := decodeField(w, "rewrite_from", req.RewriteFrom, req.KeyEncoding)
+		if !ok {
+			return
+		}
+		rewriteTo, ok := decodeField(w, "rewrite_to", req.RewriteTo, req.KeyEncoding)
+		if !ok {
+			return
+		}
+		if len(rewriteFrom) == 0 && len(rewriteTo) > 0 {
+			utils.WriteError(w, http.StatusBadRequest, "rewrite_to requires rewrite_from")
+			return
+		}
+		if req.Source == req.Target && bytes.Equal(rewriteFrom, rewriteTo) {
+			utils.WriteError(w, http.StatusBadRequest, "copying a cluster onto itself requires a prefix rewrite")
+			return
+		}
+		c := &copier{
+			source:      source.Client,
+			target:      target.Client,
+			req:         req,
+			rewriteFrom: rewriteFrom,
+			rewriteTo:   rewriteTo,
+		}
+		if req.Source == req.Target && c.rewritesInto(startKey, endKey) {
+			// The task would copy its own output again as the scan reaches it, and never finish.
+			utils.WriteError(w, http.StatusBadRequest, "the rewritten keys overlap the range being copied")
+			return
+		}
+		if req.Policy == "" {
+			req.Policy = policyOverwrite
+		}
+		if req.Policy != policyOverwrite && req.Policy != policySkipExisting {
+			utils.WriteError(w, http.StatusBadRequest, "policy must be overwrite or skip-existing")
+			return
+		}
+		if req.BatchSize <= 0 {
+			req.BatchSize = defaultCopyBatch
+		}
+		if req.BatchSize > maxBatchSize {
+			utils.WriteError(w, http.StatusBadRequest, "batch_size must not exceed "+strconv.Itoa(maxBatchSize))
+			return
+		}
+		if req.KeysPerSecond < 0 {
+			utils.WriteError(w, http.StatusBadRequest, "keys_per_second must not be negative")
+			return
+		}
+
+		c.req = req
+		caller := callerOf(r)
+		info := tasks.Start("copy", req, func(ctx context.Context, t *services.Task) error {
+			if !req.DryRun {
+				c.record = caller.batchAuditor(s, target.Name, auditCopy, map[string]any{"source": source.Name, "task_id": t.ID()})
+			}
+			return c.run(ctx, t, startKey, endKey)
+		})
+
+		if !req.DryRun {
+			recordAudit(r, s, types.AuditRecord{
+				Cluster:   target.Name,
+				Operation: auditCopy,
+				Key:       startKey,
+				Details: map[string]any{
+					"end_key":      utils.Escape(endKey),
+					"source":       source.Name,
+					"rewrite_from": utils.Escape(rewriteFrom),
+					"rewrite_to":   utils.Escape(rewriteTo),
+					"policy":       req.Policy,
+					"task_id":      info.ID,
+				},
+			})
+		}
+
+		utils.WriteJSON(w, http.StatusAccepted, info)
+	}
+}
+
+// copier copies batches of keys from the source to the target cluster.
+type copier struct {
+	source, target *rawkv.Client
+	req            types.CopyRequest
+	rewriteFrom    []byte
+	rewriteTo      []byte
+	// record audits the keys written to the target; nil on dry runs or without auditing.
+	record batchAuditFunc
+}
+
+func (c *copier) run(ctx context.Context, t *services.Task, startKey, endKey []byte) error {
+	start := time.Now()
+	copied := 0
+	for {
+		scanCtx, cancel := context.WithTimeout(ctx, copyIOTimeout)
+		keys, values, err := c.source.Scan(scanCtx, startKey, endKey, c.req.BatchSize)
+		cancel()
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+
+		targetKeys := make([][]byte, len(keys))
+		var valueBytes int64
+		for i, k := range keys {
+			targetKeys[i] = c.rewrite(k)
+			valueBytes += int64(len(values[i]))
+		}
+
+		written, skipped, err := c.write(ctx, targetKeys, values)
+		if err != nil {
+			return err
+		}
+		t.Update(func(p *types.TaskProgress) {
+			p.Scanned += int64(len(keys))
+			p.Written += int64(written)
+			p.Skipped += int64(skipped)
+			p.Bytes += valueBytes
+			p.LastKey = utils.EncodeBytes(keys[len(keys)-1], c.req.KeyEncoding)
+		})
+		copied += len(keys)
+
+		if len(keys) < c.req.BatchSize {
+			return nil
+		}
+		startKey = utils.NextKey(keys[len(keys)-1])
+
+		if err := c.throttle(ctx, start, copied); err != nil {
+			return err
+		}
+	}
+}
+
+// write stores a batch on the target, or only works out what would be written on a dry run.
+func (c *copier) write(ctx context.Context, keys, values [][]byte) (written, skipped int, err error) {
+	ctx, cancel := context.WithTimeout(ctx, copyIOTimeout)
+	defer cancel()
+
+	if !c.req.DryRun {
+		return writeBatch(ctx, c.target, keys, values, c.req.Policy, nil, c.record)
+	}
+	if c.req.Policy != policySkipExisting {
+		return len(keys), 0, nil
+	}
+	existing, err := c.target.BatchGet(ctx, keys)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, v := range existing {
+		if v != nil {
+			skipped++
+		}
+	}
+	return len(keys) - skipped, skipped, nil
+}
+
+// throttle sleeps until copying n keys since start stays within keys_per_second.
+func (c *copier) throttle(ctx context.Context, start time.Time, n int) error {
+	if c.req.KeysPerSecond == 0 {
+		return ctx.Err()
+	}
+	due := start.Add(time.Duration(float64(n) / float64(c.req.KeysPerSecond) * float64(time.Second)))
+	wait := time.Until(due)
+	if wait <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// rewritesInto reports whether rewriting the keys of [startKey, endKey) can produce keys inside
+// that same range. Empty end keys are unbounded.
+func (c *copier) rewritesInto(startKey, endKey []byte) bool {
+	// Only keys under rewrite_from change, so look at the part of the range within that prefix.
+	lo, hi := startKey, endKey
+	if bytes.Compare(c.rewriteFrom, lo) > 0 {
+		lo = c.rewriteFrom
+	}
+	prefixEnd := utils.PrefixEnd(c.rewriteFrom)
+	if prefixEnd != nil && (len(hi) == 0 || bytes.Compare(prefixEnd, hi) < 0) {
+		hi = prefixEnd
+	}
+	if len(hi) > 0 && bytes.Compare(lo, hi) >= 0 {
+		return false
+	}
+
+	// Rewriting preserves the order of the keys under the prefix, so the rewritten keys fall into
+	// [rewrite(lo), rewrite(hi)), or up to the end of rewrite_to's prefix when hi ends the prefix.
+	rewrittenLo := c.rewrite(lo)
+	rewrittenHi := utils.PrefixEnd(c.rewriteTo)
+	if len(hi) > 0 && bytes.HasPrefix(hi, c.rewriteFrom) {
+		rewrittenHi = c.rewrite(hi)
+	}
+	return (len(endKey) == 0 || bytes.Compare(rewrittenLo, endKey) < 0) &&
+		(len(rewrittenHi) == 0 || bytes.Compare(startKey, rewrittenHi) < 0)
+}
+
+// rewrite replaces the rewrite_from prefix of a key with rewrite_to; other keys are kept as is.
+func (c *copier) rewrite(key []byte) []byte {
+	if len(c.rewriteFrom) == 0 || !bytes.HasPrefix(key, c.rewriteFrom) {
+		return key
+	}
+	out := make([]byte, 0, len(key)-len(c.rewriteFrom)+len(c.rewriteTo))
+	out = append(out, c.rewriteTo...)
+	return append(out, key[len(c.rewriteFrom):]...)
+}
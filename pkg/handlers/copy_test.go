@@ -0,0 +1,28 @@
+package handlers
+
+import "testing"
+
+func TestCopierRewritesInto(t *testing.T) {
+	tests := []struct {
+		name        string
+		from, to    string
+		start, end  string
+		wantOverlap bool
+	}{
+		{"prefix grows into itself", "a", "ab", "a", "b", true},
+		{"prefix grows, open range", "a", "ab", "", "", true},
+		{"rewrite ahead of the cursor", "a/", "a/3", "a/1", "a/5", true},
+		{"rewrite behind the cursor", "b", "a", "", "", true},
+		{"disjoint prefixes", "a", "b", "a", "b", false},
+		{"range outside the prefix", "a", "ab", "x", "y", false},
+		{"rewritten range ends before start", "m", "a", "m", "n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &copier{rewriteFrom: []byte(tt.from), rewriteTo: []byte(tt.to)}
+			if got := c.rewritesInto([]byte(tt.start), []byte(tt.end)); got != tt.wantOverlap {
+				t.Errorf("rewritesInto = %v, want %v", got, tt.wantOverlap)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/GetStream/tikv-ui/pkg/server"
+	"github.com/GetStream/tikv-ui/pkg/services"
+	"github.com/GetStream/tikv-ui/pkg/types"
+	"github.com/GetStream/tikv-ui/pkg/utils"
+	"github.com/tikv/client-go/v2/rawkv"
+)
+
+const (
+	countModeApproximate = "approximate"
+	countModeExact       = "exact"
+
+	defaultCountParallelism = 4
+	maxCountParallelism     = 16
+	// countTimeout bounds an exact count, which walks every key in the range.
+	countTimeout = 5 * time.Minute
+)
+
+// Count handles requests to count the keys in a range and measure their size. The approximate
+// mode answers from PD region statistics; the exact mode scans the range region by region in
+// parallel with keys-only scans and uses TiKV checksums for the total size.
+func Count(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			utils.MethodNotAllowed(w)
+			return
+		}
+
+		var req types.CountRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		if !validateEncoding(w, req.Encoding) {
+			return
+		}
+		startKey, endKey, ok := decodeRange(w, s.KeyLayouts, req.KeyRange, req.KeyEncoding)
+		if !ok {
+			return
+		}
+		if req.Mode == "" {
+			req.Mode = countModeApproximate
+		}
+		if req.Parallelism <= 0 {
+			req.Parallelism = defaultCountParallelism
+		}
+		req.Parallelism = min(req.Parallelism, maxCountParallelism)
+
+		// Both modes work on raw keys: PD region boundaries of txn clusters are in TiKV's encoded
+		// key format, so raw bounds would select the wrong regions.
+		cli, ok := activeRawClient(w, s)
+		if !ok {
+			return
+		}
+
+		start := time.Now()
+		switch req.Mode {
+		case countModeApproximate:
+			ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+			defer cancel()
+
+			stats, err := services.RegionStats(ctx, s.GetActivePDAddr(), startKey, endKey)
+			if err != nil {
+				utils.WriteError(w, http.StatusBadGateway, "PD region stats error: "+err.Error())
+				return
+			}
+			utils.WriteJSON(w, http.StatusOK, types.CountResponse{
+				Mode:             countModeApproximate,
+				Keys:             stats.StorageKeys,
+				ApproximateBytes: stats.StorageSize * 1024 * 1024, // PD reports storage_size in MiB
+				Regions:          stats.Count,
+				ElapsedMs:        time.Since(start).Milliseconds(),
+			})
+
+		case countModeExact:
+			_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(countTimeout + 10*time.Second))
+			ctx, cancel := context.WithTimeout(r.Context(), countTimeout)
+			defer cancel()
+
+			resp, err := exactCount(ctx, cli, startKey, endKey, req.Parallelism)
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "TiKV count error: "+err.Error())
+				return
+			}
+			resp.ElapsedMs = time.Since(start).Milliseconds()
+			utils.WriteJSON(w, http.StatusOK, resp)
+
+		default:
+			utils.WriteError(w, http.StatusBadRequest, "mode must be approximate or exact")
+		}
+	}
+}
+
+// exactCount splits [startKey, endKey) at region boundaries and counts each piece concurrently.
+func exactCount(ctx context.Context, cli *rawkv.Client, startKey, endKey []byte, parallelism int) (types.CountResponse, error) {
+	ranges, err := regionRanges(ctx, cli, startKey, endKey)
+	if err != nil {
+		return types.CountResponse{}, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		resp     = types.CountResponse{Mode: countModeExact, Regions: len(ranges)}
+	)
+	sem := make(chan struct{}, parallelism)
+	for _, rng := range ranges {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(start, end []byte) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			keys, keyBytes, err := countRange(ctx, cli, start, end)
+			var checksum rawkv.RawChecksum
+			if err == nil {
+				checksum, err = cli.Checksum(ctx, start, end)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			// The scan and the checksum are separate reads, so a concurrent write can make them
+			// disagree; value_bytes is then only an estimate.
+			if checksum.TotalKvs != uint64(keys) {
+				resp.Approximate = true
+			}
+			resp.Keys += keys
+			resp.KeyBytes += keyBytes
+			resp.ValueBytes += max(int64(checksum.TotalBytes)-keyBytes, 0)
+		}(rng[0], rng[1])
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return types.CountResponse{}, firstErr
+	}
+	return resp, nil
+}
+
+// countRange counts the keys in [startKey, endKey) and their total size with keys-only scans.
+func countRange(ctx context.Context, cli *rawkv.Client, startKey, endKey []byte) (keys, keyBytes int64, err error) {
+	for {
+		page, _, err := cli.Scan(ctx, startKey, endKey, rawkv.MaxRawKVScanLimit, rawkv.ScanKeyOnly())
+		if err != nil {
+			return 0, 0, err
+		}
+		keys += int64(len(page))
+		for _, k := range page {
+			keyBytes += int64(len(k))
+		}
+		if len(page) < rawkv.MaxRawKVScanLimit {
+			return keys, keyBytes, nil
+		}
+		startKey = utils.NextKey(page[len(page)-1])
+	}
+}
+
+// regionRanges returns the pieces of [startKey, endKey) that fall into each region, in key order.
+func regionRanges(ctx context.Context, cli *rawkv.Client, startKey, endKey []byte) ([][2][]byte, error) {
+	var ranges [][2][]byte
+	cur := startKey
+	for {
+		regions, err := cli.GetPDClient().ScanRegions(ctx, cur, endKey, 1024)
+		if err != nil {
+			return nil, err
+		}
+		if len(regions) == 0 {
+			return ranges, nil
+		}
+		for _, region := range regions {
+			regionEnd := region.Meta.GetEndKey()
+			end := regionEnd
+			if len(endKey) > 0 && (len(regionEnd) == 0 || bytes.Compare(regionEnd, endKey) > 0) {
+				end = endKey
+			}
+			ranges = append(ranges, [2][]byte{cur, end})
+			if len(end) == 0 || (len(endKey) > 0 && bytes.Compare(end, endKey) >= 0) {
+				return ranges, nil
+			}
+			cur = end
+		}
+	}
+}
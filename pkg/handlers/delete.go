@@ -9,6 +9,7 @@ import (
 	"github.com/GetStream/tikv-ui/pkg/server"
 	"github.com/GetStream/tikv-ui/pkg/types"
 	"github.com/GetStream/tikv-ui/pkg/utils"
+	"github.com/tikv/client-go/v2/txnkv"
 )
 
 // Delete handles DELETE requests to remove a key from TiKV
@@ -24,19 +25,62 @@ func Delete(s *server.Server) http.HandlerFunc {
 			utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
 			return
 		}
-		if req.Key == "" {
-			utils.WriteError(w, http.StatusBadRequest, "key is required")
+		if !validateEncoding(w, req.Encoding) {
+			return
+		}
+		key, ok := decodeKey(w, s.KeyLayouts, req.KeyInput, req.KeyEncoding)
+		if !ok {
+			return
+		}
+		cf, cfOpts, err := utils.ColumnFamily(req.CF)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		conn := s.GetActiveConnection()
+		if !checkWritable(w, conn) {
+			return
+		}
+		if !checkTxnOptions(w, conn, req.CF, 0) {
 			return
 		}
 
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
 
-		if err := s.GetActiveClient().Delete(ctx, []byte(req.Key)); err != nil {
-			utils.WriteError(w, http.StatusInternalServerError, "TiKV Delete error: "+err.Error())
-			return
+		var previous []byte
+		var prevTTL uint64
+		if conn.IsTxn() {
+			err := txnWrite(ctx, conn.TxnClient, key, "", func(txn *txnkv.KVTxn) error {
+				if trackPrevious(s) {
+					var err error
+					if previous, err = txnCurrent(ctx, txn, key); err != nil {
+						return err
+					}
+				}
+				return txn.Delete(key)
+			})
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "TiKV Commit error: "+err.Error())
+				return
+			}
+		} else {
+			if trackPrevious(s) {
+				if previous, err = conn.Client.Get(ctx, key, cfOpts...); err != nil {
+					utils.WriteError(w, http.StatusInternalServerError, "TiKV Get error: "+err.Error())
+					return
+				}
+				prevTTL = previousTTL(ctx, s, conn.Client, key, cfOpts)
+			}
+			if err := conn.Client.Delete(ctx, key, cfOpts...); err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "TiKV Delete error: "+err.Error())
+				return
+			}
 		}
 
-		utils.WriteJSON(w, http.StatusOK, map[string]bool{"ok": true})
+		recordWrite(r, s, cf, types.AuditRecord{Cluster: conn.Name, Operation: auditDelete, Key: key, PreviousValue: previous, Details: auditDetails(cf)}, prevTTL)
+
+		utils.WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "cf": cf})
 	}
 }
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/GetStream/tikv-ui/pkg/server"
+	"github.com/GetStream/tikv-ui/pkg/types"
+	"github.com/GetStream/tikv-ui/pkg/utils"
+	"github.com/tikv/client-go/v2/rawkv"
+)
+
+// DeleteRange handles guarded requests to delete every key in a range. A request without a token
+// is a dry run that counts the affected keys and returns a short-lived confirmation token; sending
+// that token back with the same range executes the delete.
+func DeleteRange(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			utils.MethodNotAllowed(w)
+			return
+		}
+
+		var req types.DeleteRangeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		if !validateEncoding(w, req.Encoding) {
+			return
+		}
+		startKey, endKey, ok := decodeRange(w, s.KeyLayouts, req.KeyRange, req.KeyEncoding)
+		if !ok {
+			return
+		}
+		if (len(startKey) == 0 || len(endKey) == 0) && !req.Force {
+			utils.WriteError(w, http.StatusBadRequest, "start and end bounds are required unless force is set")
+			return
+		}
+
+		conn, ok := activeRawConnection(w, s)
+		if !ok || !checkWritable(w, conn) {
+			return
+		}
+		cli := conn.Client
+
+		// The token is bound to the cluster and the exact range it was issued for.
+		subject := conn.Name + "|" + hex.EncodeToString(startKey) + "|" + hex.EncodeToString(endKey)
+
+		if req.Token == "" {
+			ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+			defer cancel()
+
+			count, _, err := countRange(ctx, cli, startKey, endKey)
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "TiKV Scan error: "+err.Error())
+				return
+			}
+			token, expires, err := s.Confirmations.Issue(subject)
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "failed to issue token: "+err.Error())
+				return
+			}
+
+			utils.WriteJSON(w, http.StatusOK, types.DeleteRangeResponse{
+				DryRun:    true,
+				Count:     int(count),
+				Token:     token,
+				ExpiresAt: &expires,
+			})
+			return
+		}
+
+		if !s.Confirmations.Consume(req.Token, subject) {
+			utils.WriteError(w, http.StatusPreconditionFailed, "invalid or expired token for this range, run a dry run first")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+
+		record := callerOf(r).batchAuditor(s, conn.Name, auditDeleteRange, map[string]any{
+			"start_key": utils.Escape(startKey),
+			"end_key":   utils.Escape(endKey),
+		})
+		resp, resume, err := deleteRange(ctx, cli, startKey, endKey, record)
+		if err != nil {
+			// Part of the range may be gone already, so say how much and where to continue.
+			resp.Error = "TiKV DeleteRange error: " + err.Error()
+			resp.ResumeKey = utils.EncodeBytes(resume, req.KeyEncoding)
+			utils.WriteJSON(w, http.StatusInternalServerError, resp)
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, resp)
+	}
+}
+
+// rangeDeleter is the part of *rawkv.Client that deleteRange needs.
+type rangeDeleter interface {
+	Scan(ctx context.Context, startKey, endKey []byte, limit int, options ...rawkv.RawOption) ([][]byte, [][]byte, error)
+	DeleteRange(ctx context.Context, startKey, endKey []byte, options ...rawkv.RawOption) error
+}
+
+// deleteRange deletes [startKey, endKey). RawKV's DeleteRange needs a real end key: it stops as
+// soon as the start reaches the end, so an empty end would delete nothing, or only up to the end
+// of the first region. Ranges without an end are therefore deleted page by page, up to the last
+// key of each page, which also counts the keys that were deleted. Audited deletes go page by page
+// too, so that every deleted key is recorded with its value. On error the response holds what was
+// deleted so far and resume is where the keys that may remain start.
+func deleteRange(ctx context.Context, cli rangeDeleter, startKey, endKey []byte, record batchAuditFunc) (resp types.DeleteRangeResponse, resume []byte, err error) {
+	if len(endKey) > 0 && record == nil {
+		if err := cli.DeleteRange(ctx, startKey, endKey); err != nil {
+			return types.DeleteRangeResponse{}, startKey, err
+		}
+		return types.DeleteRangeResponse{Deleted: true}, nil, nil
+	}
+
+	var scanOpts []rawkv.RawOption
+	if record == nil {
+		scanOpts = append(scanOpts, rawkv.ScanKeyOnly())
+	}
+	count := 0
+	for {
+		page, values, err := cli.Scan(ctx, startKey, endKey, rawkv.MaxRawKVScanLimit, scanOpts...)
+		if err != nil {
+			return types.DeleteRangeResponse{Count: count, Deleted: count > 0}, startKey, err
+		}
+		if len(page) == 0 {
+			return types.DeleteRangeResponse{Count: count, Deleted: count > 0}, nil, nil
+		}
+		next := utils.NextKey(page[len(page)-1])
+		err = cli.DeleteRange(ctx, startKey, next)
+		if record != nil {
+			record(page, values, nil, err)
+		}
+		if err != nil {
+			return types.DeleteRangeResponse{Count: count, Deleted: count > 0}, startKey, err
+		}
+		count += len(page)
+		startKey = next
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/tikv/client-go/v2/rawkv"
+)
+
+// fakeRangeStore is an in-memory rangeDeleter. Like client-go's DeleteRange, it deletes nothing
+// when the start and end keys are equal, which includes two empty keys.
+type fakeRangeStore struct {
+	keys [][]byte
+	// deletesLeft makes DeleteRange fail once it reaches zero; negative means never.
+	deletesLeft int
+}
+
+func (f *fakeRangeStore) Scan(ctx context.Context, startKey, endKey []byte, limit int, options ...rawkv.RawOption) ([][]byte, [][]byte, error) {
+	var out [][]byte
+	for _, k := range f.keys {
+		if bytes.Compare(k, startKey) >= 0 && (len(endKey) == 0 || bytes.Compare(k, endKey) < 0) && len(out) < limit {
+			out = append(out, k)
+		}
+	}
+	return out, make([][]byte, len(out)), nil
+}
+
+func (f *fakeRangeStore) DeleteRange(ctx context.Context, startKey, endKey []byte, options ...rawkv.RawOption) error {
+	if bytes.Equal(startKey, endKey) {
+		return nil
+	}
+	if f.deletesLeft == 0 {
+		return context.DeadlineExceeded
+	}
+	f.deletesLeft--
+	kept := f.keys[:0]
+	for _, k := range f.keys {
+		if bytes.Compare(k, startKey) < 0 || bytes.Compare(k, endKey) >= 0 {
+			kept = append(kept, k)
+		}
+	}
+	f.keys = kept
+	return nil
+}
+
+func newFakeRangeStore(n int) *fakeRangeStore {
+	f := &fakeRangeStore{deletesLeft: -1}
+	for i := 0; i < n; i++ {
+		f.keys = append(f.keys, []byte{byte(i >> 16), byte(i >> 8), byte(i)})
+	}
+	sort.Slice(f.keys, func(i, j int) bool { return bytes.Compare(f.keys[i], f.keys[j]) < 0 })
+	return f
+}
+
+func TestDeleteRange(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("open range", func(t *testing.T) {
+		f := newFakeRangeStore(rawkv.MaxRawKVScanLimit + 10)
+		resp, _, err := deleteRange(ctx, f, nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.Deleted || resp.Count != rawkv.MaxRawKVScanLimit+10 {
+			t.Errorf("response = %+v, want all keys deleted", resp)
+		}
+		if len(f.keys) != 0 {
+			t.Errorf("%d keys left", len(f.keys))
+		}
+	})
+
+	t.Run("open end", func(t *testing.T) {
+		f := newFakeRangeStore(20)
+		resp, _, err := deleteRange(ctx, f, []byte{0, 0, 5}, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.Deleted || resp.Count != 15 || len(f.keys) != 5 {
+			t.Errorf("response = %+v with %d keys left, want 15 deleted and 5 left", resp, len(f.keys))
+		}
+	})
+
+	t.Run("empty open range", func(t *testing.T) {
+		resp, _, err := deleteRange(ctx, newFakeRangeStore(0), nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Deleted || resp.Count != 0 {
+			t.Errorf("response = %+v, want nothing deleted", resp)
+		}
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		f := newFakeRangeStore(20)
+		resp, _, err := deleteRange(ctx, f, []byte{0, 0, 5}, []byte{0, 0, 10}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.Deleted || len(f.keys) != 15 {
+			t.Errorf("response = %+v with %d keys left, want 15 left", resp, len(f.keys))
+		}
+	})
+
+	t.Run("audited", func(t *testing.T) {
+		f := newFakeRangeStore(20)
+		var recorded [][]byte
+		record := func(keys, previous, values [][]byte, err error) {
+			if len(previous) != len(keys) || values != nil || err != nil {
+				t.Errorf("record(%d keys, %d previous, %v, %v)", len(keys), len(previous), values, err)
+			}
+			recorded = append(recorded, keys...)
+		}
+		resp, _, err := deleteRange(ctx, f, []byte{0, 0, 5}, []byte{0, 0, 10}, record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.Deleted || resp.Count != 5 || len(recorded) != 5 || len(f.keys) != 15 {
+			t.Errorf("response = %+v, %d keys recorded, %d left; want 5 deleted and recorded", resp, len(recorded), len(f.keys))
+		}
+	})
+
+	t.Run("fails part way", func(t *testing.T) {
+		f := newFakeRangeStore(2*rawkv.MaxRawKVScanLimit + 10)
+		f.deletesLeft = 1
+		resp, resume, err := deleteRange(ctx, f, nil, nil, nil)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if !resp.Deleted || resp.Count != rawkv.MaxRawKVScanLimit {
+			t.Errorf("response = %+v, want the first page reported as deleted", resp)
+		}
+		if len(f.keys) != rawkv.MaxRawKVScanLimit+10 || bytes.Compare(resume, f.keys[0]) > 0 {
+			t.Errorf("resume = %x with %d keys left, want a key at or before the first left %x", resume, len(f.keys), f.keys[0])
+		}
+	})
+
+	t.Run("bounded fails", func(t *testing.T) {
+		f := newFakeRangeStore(20)
+		f.deletesLeft = 0
+		start := []byte{0, 0, 5}
+		resp, resume, err := deleteRange(ctx, f, start, []byte{0, 0, 10}, nil)
+		if err == nil || resp.Deleted || !bytes.Equal(resume, start) {
+			t.Errorf("deleteRange = %+v, %x, %v; want an error resuming at the start", resp, resume, err)
+		}
+	})
+}
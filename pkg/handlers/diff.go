@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/GetStream/tikv-ui/pkg/server"
+	"github.com/GetStream/tikv-ui/pkg/types"
+	"github.com/GetStream/tikv-ui/pkg/utils"
+	"github.com/tikv/client-go/v2/rawkv"
+)
+
+const (
+	diffCompareRaw    = "raw"
+	diffCompareParsed = "parsed"
+
+	diffPageSize = 1000
+)
+
+// Diff handles requests to compare a key range between two registered clusters. Both clusters
+// are walked in key order and the keys missing on either side or holding different values are
+// reported, together with a summary of everything examined.
+func Diff(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			utils.MethodNotAllowed(w)
+			return
+		}
+
+		var req types.DiffRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		if req.ClusterA == "" || req.ClusterB == "" {
+			utils.WriteError(w, http.StatusBadRequest, "cluster_a and cluster_b are required")
+			return
+		}
+		connA, ok := rawCluster(w, s, req.ClusterA)
+		if !ok {
+			return
+		}
+		connB, ok := rawCluster(w, s, req.ClusterB)
+		if !ok {
+			return
+		}
+		if !validateEncoding(w, req.Encoding) {
+			return
+		}
+		startKey, endKey, ok := decodeRange(w, s.KeyLayouts, req.KeyRange, req.KeyEncoding)
+		if !ok {
+			return
+		}
+		if req.Cursor != "" {
+			cursor, err := utils.DecodeCursor(req.Cursor)
+			if err != nil || cursor.Reverse {
+				utils.WriteError(w, http.StatusBadRequest, "invalid cursor")
+				return
+			}
+			startKey, endKey = cursor.Resume(startKey, endKey)
+		}
+		if req.Compare == "" {
+			req.Compare = diffCompareRaw
+		}
+		if req.Compare != diffCompareRaw && req.Compare != diffCompareParsed {
+			utils.WriteError(w, http.StatusBadRequest, "compare must be raw or parsed")
+			return
+		}
+		if req.Limit <= 0 {
+			req.Limit = 100
+		}
+		if req.Limit > maxBatchSize {
+			utils.WriteError(w, http.StatusBadRequest, "limit must not exceed "+strconv.Itoa(maxBatchSize))
+			return
+		}
+		if req.ScanBudget <= 0 {
+			req.ScanBudget = defaultScanBudget
+		}
+		if req.ScanBudget > maxScanBudget {
+			utils.WriteError(w, http.StatusBadRequest, "scan_budget must not exceed "+strconv.Itoa(maxScanBudget))
+			return
+		}
+
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(40 * time.Second))
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+
+		d := &differ{
+			a:       connA.Client,
+			b:       connB.Client,
+			req:     &req,
+			entries: make([]types.DiffEntry, 0),
+		}
+		exhausted, err := d.run(ctx, startKey, endKey)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "TiKV Scan error: "+err.Error())
+			return
+		}
+
+		resp := types.DiffResponse{Entries: d.entries, Summary: d.summary}
+		if !exhausted {
+			resp.HasMore = true
+			resp.NextCursor = utils.EncodeCursor(utils.ScanCursor{Key: d.last})
+		}
+		utils.WriteJSON(w, http.StatusOK, resp)
+	}
+}
+
+// differ merges the key streams of two clusters and collects their differences.
+type differ struct {
+	a, b    *rawkv.Client
+	req     *types.DiffRequest
+	entries []types.DiffEntry
+	summary types.DiffSummary
+	last    []byte
+}
+
+// run walks [startKey, endKey) on both clusters until the range is exhausted, the entry limit
+// is reached or the scan budget is spent. It reports whether the whole range was examined.
+func (d *differ) run(ctx context.Context, startKey, endKey []byte) (bool, error) {
+	for {
+		keysA, valuesA, err := d.a.Scan(ctx, startKey, endKey, diffPageSize)
+		if err != nil {
+			return false, err
+		}
+		keysB, valuesB, err := d.b.Scan(ctx, startKey, endKey, diffPageSize)
+		if err != nil {
+			return false, err
+		}
+
+		// Only keys up to the smaller last key of a full page are known on both sides;
+		// a nil bound means both pages reached the end of the range.
+		var bound []byte
+		if len(keysA) == diffPageSize {
+			bound = keysA[len(keysA)-1]
+		}
+		if len(keysB) == diffPageSize && (bound == nil || bytes.Compare(keysB[len(keysB)-1], bound) < 0) {
+			bound = keysB[len(keysB)-1]
+		}
+		inBound := func(k []byte) bool {
+			return bound == nil || bytes.Compare(k, bound) <= 0
+		}
+
+		i, j := 0, 0
+		for {
+			hasA := i < len(keysA) && inBound(keysA[i])
+			hasB := j < len(keysB) && inBound(keysB[j])
+			if !hasA && !hasB {
+				break
+			}
+
+			switch {
+			case hasA && (!hasB || bytes.Compare(keysA[i], keysB[j]) < 0):
+				d.last = keysA[i]
+				d.summary.OnlyA++
+				d.add(keysA[i], types.DiffOnlyA, valuesA[i], nil, nil)
+				i++
+			case hasB && (!hasA || bytes.Compare(keysB[j], keysA[i]) < 0):
+				d.last = keysB[j]
+				d.summary.OnlyB++
+				d.add(keysB[j], types.DiffOnlyB, nil, valuesB[j], nil)
+				j++
+			default:
+				d.last = keysA[i]
+				d.compare(keysA[i], valuesA[i], valuesB[j])
+				i++
+				j++
+			}
+			d.summary.Scanned++
+
+			if len(d.entries) >= d.req.Limit || d.summary.Scanned >= d.req.ScanBudget {
+				done := bound == nil && i == len(keysA) && j == len(keysB)
+				return done, nil
+			}
+		}
+
+		if bound == nil {
+			return true, nil
+		}
+		startKey = utils.NextKey(bound)
+	}
+}
+
+// compare records a key present on both clusters, checking raw bytes first and decoded values second.
+func (d *differ) compare(key, valueA, valueB []byte) {
+	if bytes.Equal(valueA, valueB) {
+		d.summary.Identical++
+		return
+	}
+	d.summary.RawDifferent++
+
+	parsedEqual := reflect.DeepEqual(parsedValue(valueA), parsedValue(valueB))
+	if !parsedEqual {
+		d.summary.ParsedDifferent++
+	}
+	if d.req.Compare == diffCompareParsed && parsedEqual {
+		return
+	}
+	d.add(key, types.DiffValueDiffers, valueA, valueB, &parsedEqual)
+}
+
+func (d *differ) add(key []byte, kind string, valueA, valueB []byte, parsedEqual *bool) {
+	entry := types.DiffEntry{
+		Key:         utils.EncodeBytes(key, d.req.KeyEncoding),
+		KeyBase64:   utils.EncodeBytes(key, utils.EncodingBase64),
+		Kind:        kind,
+		ParsedEqual: parsedEqual,
+	}
+	if valueA != nil {
+		v := utils.FormatValue(valueA, d.req.ValueEncoding)
+		entry.ValueA = &v
+	}
+	if valueB != nil {
+		v := utils.FormatValue(valueB, d.req.ValueEncoding)
+		entry.ValueB = &v
+	}
+	d.entries = append(d.entries, entry)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/GetStream/tikv-ui/pkg/types"
+	"github.com/GetStream/tikv-ui/pkg/utils"
+)
+
+// validateEncoding checks both encodings of a request, writing a 400 response on failure.
+func validateEncoding(w http.ResponseWriter, enc types.Encoding) bool {
+	if err := utils.ValidateEncoding(enc.KeyEncoding); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "key_encoding: "+err.Error())
+		return false
+	}
+	if err := utils.ValidateEncoding(enc.ValueEncoding); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "value_encoding: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// decodeField decodes an encoded request field, writing a 400 response naming the field on failure.
+func decodeField(w http.ResponseWriter, field, value, enc string) ([]byte, bool) {
+	b, err := utils.DecodeBytes(value, enc)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid "+field+": "+err.Error())
+		return nil, false
+	}
+	return b, true
+}
+
+// decodeKey decodes the key of a single-key request, given either encoded or in one of the structured forms.
+func decodeKey(w http.ResponseWriter, layouts *utils.KeyLayouts, in types.KeyInput, enc string) ([]byte, bool) {
+	given := 0
+	for _, set := range []bool{in.Key != "", in.TiDBKey != nil, in.StructuredKey != nil} {
+		if set {
+			given++
+		}
+	}
+	if given > 1 {
+		utils.WriteError(w, http.StatusBadRequest, "key, tidb_key and structured_key are mutually exclusive")
+		return nil, false
+	}
+
+	switch {
+	case in.TiDBKey != nil:
+		b, err := utils.EncodeTiDBKey(in.TiDBKey)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid tidb_key: "+err.Error())
+			return nil, false
+		}
+		return b, true
+	case in.StructuredKey != nil:
+		b, err := layouts.Encode(in.StructuredKey)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid structured_key: "+err.Error())
+			return nil, false
+		}
+		return b, true
+	case in.Key == "":
+		utils.WriteError(w, http.StatusBadRequest, "key is required")
+		return nil, false
+	}
+	return decodeField(w, "key", in.Key, enc)
+}
+
+// decodeRange decodes the bounds of a key range, deriving them from the prefix when one is set.
+func decodeRange(w http.ResponseWriter, layouts *utils.KeyLayouts, rng types.KeyRange, enc string) (startKey, endKey []byte, ok bool) {
+	if rng.TiDBPrefix != nil || rng.StructuredPrefix != nil {
+		if (rng.TiDBPrefix != nil && rng.StructuredPrefix != nil) || rng.Prefix != "" || rng.StartKey != "" || rng.EndKey != "" {
+			utils.WriteError(w, http.StatusBadRequest, "tidb_prefix and structured_prefix cannot be combined with each other or with prefix, start_key or end_key")
+			return nil, nil, false
+		}
+		field := "tidb_prefix"
+		var prefix []byte
+		var err error
+		if rng.TiDBPrefix != nil {
+			prefix, err = utils.EncodeTiDBKey(rng.TiDBPrefix)
+		} else {
+			field = "structured_prefix"
+			prefix, err = layouts.Encode(rng.StructuredPrefix)
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid "+field+": "+err.Error())
+			return nil, nil, false
+		}
+		return prefix, utils.PrefixEnd(prefix), true
+	}
+	if rng.Prefix != "" {
+		if rng.StartKey != "" || rng.EndKey != "" {
+			utils.WriteError(w, http.StatusBadRequest, "prefix cannot be combined with start_key or end_key")
+			return nil, nil, false
+		}
+		prefix, ok := decodeField(w, "prefix", rng.Prefix, enc)
+		if !ok {
+			return nil, nil, false
+		}
+		return prefix, utils.PrefixEnd(prefix), true
+	}
+
+	if startKey, ok = decodeField(w, "start_key", rng.StartKey, enc); !ok {
+		return nil, nil, false
+	}
+	if endKey, ok = decodeField(w, "end_key", rng.EndKey, enc); !ok {
+		return nil, nil, false
+	}
+	return startKey, endKey, true
+}
+
+// newKeyItem builds a scan result item that carries only the key, decoded when it has a known layout.
+func newKeyItem(key []byte, enc types.Encoding, layouts *utils.KeyLayouts) types.ScanItem {
+	item := types.ScanItem{
+		Key:       utils.EncodeBytes(key, enc.KeyEncoding),
+		KeyBase64: utils.EncodeBytes(key, utils.EncodingBase64),
+	}
+	item.TiDB, _ = utils.DecodeTiDBKey(key)
+	item.Structured = layouts.Decode(key)
+	return item
+}
+
+// viewValue decodes a value for a response, with ext values in full when the request asks for it.
+func viewValue(value []byte, enc types.Encoding) any {
+	if enc.MsgpackExt {
+		parsed, _ := utils.ParseValueWithExt(value)
+		return parsed
+	}
+	return parsedValue(value)
+}
+
+// newScanItem builds a scan result item, encoding the key and raw value as requested.
+// Values longer than previewBytes (when positive) are truncated and left unparsed. parsed is the
+// value as decoded by viewValue when the caller already has it, or nil to decode it here.
+func newScanItem(key, value []byte, parsed any, enc types.Encoding, previewBytes int, layouts *utils.KeyLayouts) types.ScanItem {
+	item := newKeyItem(key, enc, layouts)
+	size := len(value)
+	item.ValueSize = &size
+
+	if previewBytes > 0 && len(value) > previewBytes {
+		item.RawValue = utils.FormatValue(value[:previewBytes], enc.ValueEncoding)
+		item.Truncated = true
+		return item
+	}
+
+	if parsed == nil {
+		parsed = viewValue(value, enc)
+	}
+	item.Value = parsed
+	item.RawValue = utils.FormatValue(value, enc.ValueEncoding)
+	return item
+}
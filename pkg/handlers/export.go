@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/GetStream/tikv-ui/pkg/server"
+	"github.com/GetStream/tikv-ui/pkg/types"
+	"github.com/GetStream/tikv-ui/pkg/utils"
+	"github.com/tikv/client-go/v2/rawkv"
+)
+
+const (
+	// exportTimeout bounds a whole export, independently of the server's WriteTimeout.
+	exportTimeout = time.Hour
+	// exportPageTimeout bounds a single TiKV scan while exporting.
+	exportPageTimeout = 10 * time.Second
+	// exportWriteTimeout is the time a client gets to accept each page before the export is aborted.
+	exportWriteTimeout = 30 * time.Second
+	defaultExportPage  = 1000
+)
+
+// Export handles requests to stream a key range as newline-delimited JSON. The range is read
+// page by page and each page is flushed before the next one is fetched, so a slow client slows
+// the export down instead of growing server memory. The export stops when the client disconnects.
+func Export(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			utils.MethodNotAllowed(w)
+			return
+		}
+
+		var req types.ExportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		// Exports default to base64 so every line round-trips through import.
+		if req.KeyEncoding == "" {
+			req.KeyEncoding = utils.EncodingBase64
+		}
+		if req.ValueEncoding == "" {
+			req.ValueEncoding = utils.EncodingBase64
+		}
+		if !validateEncoding(w, req.Encoding) {
+			return
+		}
+		startKey, endKey, ok := decodeRange(w, s.KeyLayouts, req.KeyRange, req.KeyEncoding)
+		if !ok {
+			return
+		}
+		_, cfOpts, err := utils.ColumnFamily(req.CF)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if req.PageSize <= 0 {
+			req.PageSize = defaultExportPage
+		}
+		if req.PageSize > rawkv.MaxRawKVScanLimit {
+			utils.WriteError(w, http.StatusBadRequest, "page_size must not exceed "+strconv.Itoa(rawkv.MaxRawKVScanLimit))
+			return
+		}
+
+		cli, ok := activeRawClient(w, s)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), exportTimeout)
+		defer cancel()
+
+		rc := http.NewResponseController(w)
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+
+		exported := 0
+		for {
+			// Each page extends the write deadline instead of relying on the server-wide WriteTimeout.
+			if err := rc.SetWriteDeadline(time.Now().Add(exportPageTimeout + exportWriteTimeout)); err != nil && err != http.ErrNotSupported {
+				return
+			}
+			pageCtx, pageCancel := context.WithTimeout(ctx, exportPageTimeout)
+			keys, values, err := cli.Scan(pageCtx, startKey, endKey, req.PageSize, cfOpts...)
+			pageCancel()
+			if err != nil {
+				// The status line is already sent, so the failure is reported as a final line.
+				_ = enc.Encode(map[string]string{"error": "TiKV Scan error: " + err.Error()})
+				_ = bw.Flush()
+				log.Printf("export aborted after %d keys: %v", exported, err)
+				return
+			}
+
+			for i := range keys {
+				line := types.ExportItem{
+					Key:   utils.EncodeBytes(keys[i], req.KeyEncoding),
+					Value: utils.EncodeBytes(values[i], req.ValueEncoding),
+				}
+				if req.IncludeParsed {
+					line.Parsed = parsedValue(values[i])
+				}
+				if err := enc.Encode(line); err != nil {
+					return
+				}
+			}
+			exported += len(keys)
+
+			if err := bw.Flush(); err != nil {
+				log.Printf("export aborted after %d keys: %v", exported, err)
+				return
+			}
+			if err := rc.Flush(); err != nil && err != http.ErrNotSupported {
+				return
+			}
+
+			if len(keys) < req.PageSize {
+				return
+			}
+			startKey = utils.NextKey(keys[len(keys)-1])
+		}
+	}
+}
@@ -24,29 +24,76 @@ func Get(s *server.Server) http.HandlerFunc {
 			utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
 			return
 		}
-		if req.Key == "" {
-			utils.WriteError(w, http.StatusBadRequest, "key is required")
+		if !validateEncoding(w, req.Encoding) {
+			return
+		}
+		key, ok := decodeKey(w, s.KeyLayouts, req.KeyInput, req.KeyEncoding)
+		if !ok {
+			return
+		}
+		cf, cfOpts, err := utils.ColumnFamily(req.CF)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		conn := s.GetActiveConnection()
+		if !checkTxnOptions(w, conn, req.CF, req.SnapshotTS) {
 			return
 		}
 
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
 
-		val, err := s.GetActiveClient().Get(ctx, []byte(req.Key))
-		if err != nil {
+		var val []byte
+		var snapshotTS uint64
+		if conn.IsTxn() {
+			snap, ts, err := txnSnapshot(ctx, conn.TxnClient, req.SnapshotTS)
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "TiKV snapshot error: "+err.Error())
+				return
+			}
+			val, err = txnGet(ctx, snap, key)
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "TiKV Get error: "+err.Error())
+				return
+			}
+			snapshotTS = ts
+		} else if val, err = conn.Client.Get(ctx, key, cfOpts...); err != nil {
 			utils.WriteError(w, http.StatusInternalServerError, "TiKV Get error: "+err.Error())
 			return
 		}
 
-		resp := types.GetResponse{
-			Key: req.Key,
-		}
+		resp := newGetResponse(key, val, req.Encoding, s.KeyLayouts)
+		resp.CF = cf
+		resp.SnapshotTS = snapshotTS
 		if val != nil {
-			resp.Value, _ = utils.ParseValue(val)
-			resp.RawValue = utils.FormatRawValue(val)
-			resp.Found = true
+			w.Header().Set("ETag", `"`+resp.ETag+`"`)
+			// Clusters without TTL support reject GetKeyTTL; the value is still worth returning.
+			if conn.Client != nil {
+				if ttl, err := conn.Client.GetKeyTTL(ctx, key, cfOpts...); err == nil && ttl != nil && *ttl > 0 {
+					resp.TTLSeconds = ttl
+				}
+			}
 		}
 
 		utils.WriteJSON(w, http.StatusOK, resp)
 	}
 }
+
+// newGetResponse builds the response for a single key; a nil value means the key was not found.
+func newGetResponse(key, val []byte, enc types.Encoding, layouts *utils.KeyLayouts) types.GetResponse {
+	resp := types.GetResponse{
+		Key:       utils.EncodeBytes(key, enc.KeyEncoding),
+		KeyBase64: utils.EncodeBytes(key, utils.EncodingBase64),
+	}
+	resp.TiDB, _ = utils.DecodeTiDBKey(key)
+	resp.Structured = layouts.Decode(key)
+	if val != nil {
+		resp.Value = viewValue(val, enc)
+		resp.RawValue = utils.FormatValue(val, enc.ValueEncoding)
+		resp.ETag = utils.ETag(val)
+		resp.Found = true
+	}
+	return resp
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/GetStream/tikv-ui/pkg/server"
+	"github.com/GetStream/tikv-ui/pkg/types"
+	"github.com/GetStream/tikv-ui/pkg/utils"
+	"github.com/tikv/client-go/v2/rawkv"
+	"github.com/tikv/client-go/v2/txnkv"
+)
+
+const auditRevert = "revert"
+
+// trackPrevious reports whether writes need to read the value they replace, for the audit log
+// or the history.
+func trackPrevious(s *server.Server) bool {
+	return s.Audit.Enabled() || s.History.Enabled()
+}
+
+// previousTTL returns the time to live key has left before a raw write replaces it, or 0 when it
+// has none. Only the history needs it, so a revert can restore the TTL with the value.
+func previousTTL(ctx context.Context, s *server.Server, cli *rawkv.Client, key []byte, cfOpts []rawkv.RawOption) uint64 {
+	if !s.History.Enabled() {
+		return 0
+	}
+	return keyTTL(ctx, cli, key, cfOpts)
+}
+
+// recordWrite records a single-key write in the audit log and in the history, so it can be
+// reverted. prevTTL is the TTL the replaced value had left, from previousTTL.
+func recordWrite(r *http.Request, s *server.Server, cf string, rec types.AuditRecord, prevTTL uint64) {
+	recordAudit(r, s, rec)
+	s.History.Add(utils.HistoryEntry{
+		Time:          time.Now().UTC(),
+		Cluster:       rec.Cluster,
+		Operation:     rec.Operation,
+		CF:            cf,
+		Key:           rec.Key,
+		PreviousValue: rec.PreviousValue,
+		NewValue:      rec.NewValue,
+		PreviousTTL:   prevTTL,
+		Identity:      server.CallerIdentity(r.Context()),
+	})
+}
+
+// History handles requests to list a cluster's recent writes. Query parameters: cluster (the
+// active one by default), key (with key_encoding), value_encoding and limit.
+func History(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			utils.MethodNotAllowed(w)
+			return
+		}
+		if !s.History.Enabled() {
+			utils.WriteError(w, http.StatusNotFound, "write history is not enabled")
+			return
+		}
+
+		q := r.URL.Query()
+		enc := types.Encoding{KeyEncoding: q.Get("key_encoding"), ValueEncoding: q.Get("value_encoding")}
+		if !validateEncoding(w, enc) {
+			return
+		}
+		var key []byte
+		if k := q.Get("key"); k != "" {
+			var ok bool
+			if key, ok = decodeField(w, "key", k, enc.KeyEncoding); !ok {
+				return
+			}
+		}
+		limit := 0
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				utils.WriteError(w, http.StatusBadRequest, "limit must be a positive number")
+				return
+			}
+			limit = n
+		}
+		cluster := q.Get("cluster")
+		if cluster == "" {
+			cluster = s.GetActiveClusterName()
+		}
+
+		entries := s.History.List(cluster, key)
+		if limit > 0 && len(entries) > limit {
+			entries = entries[:limit]
+		}
+		items := make([]types.HistoryItem, len(entries))
+		for i, e := range entries {
+			items[i] = types.HistoryItem{
+				ID:                 e.ID,
+				Time:               e.Time,
+				Operation:          e.Operation,
+				CF:                 e.CF,
+				Key:                utils.EncodeBytes(e.Key, enc.KeyEncoding),
+				PreviousValue:      formatAuditValue(e.PreviousValue, enc.ValueEncoding),
+				NewValue:           formatAuditValue(e.NewValue, enc.ValueEncoding),
+				PreviousTTLSeconds: e.PreviousTTL,
+				Identity:           e.Identity,
+			}
+		}
+		utils.WriteJSON(w, http.StatusOK, types.HistoryResponse{Cluster: cluster, Items: items})
+	}
+}
+
+// Revert handles requests to undo a recorded write by restoring the value it replaced. The
+// revert only applies while the key still holds the value that write stored, so a newer change
+// is reported with 409 instead of being overwritten.
+func Revert(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			utils.MethodNotAllowed(w)
+			return
+		}
+
+		var req types.RevertRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		if req.ID == 0 {
+			utils.WriteError(w, http.StatusBadRequest, "id is required")
+			return
+		}
+		if !validateEncoding(w, req.Encoding) {
+			return
+		}
+		if req.Cluster == "" {
+			req.Cluster = s.GetActiveClusterName()
+		}
+		conn, ok := s.GetCluster(req.Cluster)
+		if !ok {
+			utils.WriteError(w, http.StatusNotFound, "cluster '"+req.Cluster+"' not found")
+			return
+		}
+		if !checkWritable(w, conn) {
+			return
+		}
+		entry, ok := s.History.Get(req.Cluster, req.ID)
+		if !ok {
+			utils.WriteError(w, http.StatusNotFound, "history entry not found")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		var err error
+		var prevTTL uint64
+		if conn.IsTxn() {
+			err = txnWrite(ctx, conn.TxnClient, entry.Key, "", func(txn *txnkv.KVTxn) error {
+				current, err := txnCurrent(ctx, txn, entry.Key)
+				if err != nil {
+					return err
+				}
+				if !sameValue(current, entry.NewValue) {
+					return errValueChanged
+				}
+				if entry.PreviousValue == nil {
+					return txn.Delete(entry.Key)
+				}
+				return txn.Set(entry.Key, entry.PreviousValue)
+			})
+		} else {
+			_, cfOpts, _ := utils.ColumnFamily(entry.CF)
+			prevTTL = previousTTL(ctx, s, conn.Client, entry.Key, cfOpts)
+			err = rawRevert(ctx, conn.Client, entry)
+		}
+		if errors.Is(err, errValueChanged) {
+			utils.WriteError(w, http.StatusConflict, "key has changed since this write, revert the newer write first")
+			return
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "TiKV error: "+err.Error())
+			return
+		}
+
+		rec := types.AuditRecord{
+			Cluster:       conn.Name,
+			Operation:     auditRevert,
+			Key:           entry.Key,
+			PreviousValue: entry.NewValue,
+			NewValue:      entry.PreviousValue,
+			Details:       auditDetails(entry.CF),
+		}
+		if rec.Details == nil {
+			rec.Details = map[string]any{}
+		}
+		rec.Details["reverted_id"] = entry.ID
+		recordWrite(r, s, entry.CF, rec, prevTTL)
+
+		utils.WriteJSON(w, http.StatusOK, newGetResponse(entry.Key, entry.PreviousValue, req.Encoding, s.KeyLayouts))
+	}
+}
+
+// rawRevert restores the value entry replaced, with the TTL it had left, if the key still holds
+// entry.NewValue. RawKV has no conditional delete and its CompareAndSwap cannot set a TTL, so
+// undoing the creation of a key or restoring a value with a TTL checks the value and writes in
+// two steps; every other revert is a single CompareAndSwap.
+func rawRevert(ctx context.Context, cli *rawkv.Client, entry utils.HistoryEntry) error {
+	_, cfOpts, err := utils.ColumnFamily(entry.CF)
+	if err != nil {
+		return err
+	}
+
+	if entry.PreviousValue != nil && entry.PreviousTTL == 0 {
+		_, swapped, err := cli.CompareAndSwap(ctx, entry.Key, entry.NewValue, entry.PreviousValue, cfOpts...)
+		if err != nil {
+			return err
+		}
+		if !swapped {
+			return errValueChanged
+		}
+		return nil
+	}
+
+	current, err := cli.Get(ctx, entry.Key, cfOpts...)
+	if err != nil {
+		return err
+	}
+	if !sameValue(current, entry.NewValue) {
+		return errValueChanged
+	}
+	if entry.PreviousValue != nil {
+		return cli.PutWithTTL(ctx, entry.Key, entry.PreviousValue, entry.PreviousTTL, cfOpts...)
+	}
+	return cli.Delete(ctx, entry.Key, cfOpts...)
+}
+
+// sameValue compares two values where nil means the key does not exist.
+func sameValue(a, b []byte) bool {
+	return (a == nil) == (b == nil) && bytes.Equal(a, b)
+}
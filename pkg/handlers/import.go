@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GetStream/tikv-ui/pkg/server"
+	"github.com/GetStream/tikv-ui/pkg/types"
+	"github.com/GetStream/tikv-ui/pkg/utils"
+	"github.com/tikv/client-go/v2/rawkv"
+)
+
+// Write policies for bulk operations that may hit existing keys.
+const (
+	policyOverwrite    = "overwrite"
+	policySkipExisting = "skip-existing"
+)
+
+const (
+	defaultImportBatch = 256
+	// importIOTimeout is the time allowed to read and write each batch of an import.
+	importIOTimeout = 30 * time.Second
+	// maxImportErrors caps how many individual error messages are reported back.
+	maxImportErrors = 20
+)
+
+// Import handles bulk uploads of key-value pairs as NDJSON or CSV. The body is the file itself,
+// options are passed as query parameters, and progress is streamed back as one NDJSON line per
+// written batch followed by a final summary line.
+func Import(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			utils.MethodNotAllowed(w)
+			return
+		}
+
+		q := r.URL.Query()
+		format := q.Get("format")
+		if format == "" {
+			format = "ndjson"
+		}
+		if format != "ndjson" && format != "csv" {
+			utils.WriteError(w, http.StatusBadRequest, "format must be ndjson or csv")
+			return
+		}
+		// Imports default to base64, matching the export endpoint.
+		enc := types.Encoding{KeyEncoding: q.Get("key_encoding"), ValueEncoding: q.Get("value_encoding")}
+		if enc.KeyEncoding == "" {
+			enc.KeyEncoding = utils.EncodingBase64
+		}
+		if enc.ValueEncoding == "" {
+			enc.ValueEncoding = utils.EncodingBase64
+		}
+		if !validateEncoding(w, enc) {
+			return
+		}
+		policy := q.Get("policy")
+		if policy == "" {
+			policy = policyOverwrite
+		}
+		if policy != policyOverwrite && policy != policySkipExisting {
+			utils.WriteError(w, http.StatusBadRequest, "policy must be overwrite or skip-existing")
+			return
+		}
+		batchSize := defaultImportBatch
+		if v := q.Get("batch_size"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 || n > maxBatchSize {
+				utils.WriteError(w, http.StatusBadRequest, "batch_size must be between 1 and "+strconv.Itoa(maxBatchSize))
+				return
+			}
+			batchSize = n
+		}
+		cf, cfOpts, err := utils.ColumnFamily(q.Get("cf"))
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		conn, ok := activeRawConnection(w, s)
+		if !ok || !checkWritable(w, conn) {
+			return
+		}
+		im := &importer{
+			cli:       conn.Client,
+			format:    format,
+			enc:       enc,
+			policy:    policy,
+			batchSize: batchSize,
+			cfOpts:    cfOpts,
+			record:    callerOf(r).batchAuditor(s, conn.Name, auditImport, auditDetails(cf)),
+		}
+		progress := im.run(w, r)
+
+		// Besides the entry of each written key, the import is recorded as a whole once it stops,
+		// however it ends.
+		if progress.Written == 0 && progress.Failed == 0 {
+			return
+		}
+		recordAudit(r, s, types.AuditRecord{
+			Cluster:   conn.Name,
+			Operation: auditImport,
+			Details: map[string]any{
+				"format":    format,
+				"policy":    policy,
+				"read":      progress.Read,
+				"written":   progress.Written,
+				"skipped":   progress.Skipped,
+				"failed":    progress.Failed,
+				"truncated": progress.Truncated,
+				"done":      progress.Done,
+			},
+		})
+	}
+}
+
+// batchStore is the part of a RawKV client used to write batches.
+type batchStore interface {
+	BatchGet(ctx context.Context, keys [][]byte, options ...rawkv.RawOption) ([][]byte, error)
+	BatchPut(ctx context.Context, keys, values [][]byte, options ...rawkv.RawOption) error
+}
+
+// importer writes the records of an uploaded file in batches.
+type importer struct {
+	cli       batchStore
+	format    string
+	enc       types.Encoding
+	policy    string
+	batchSize int
+	cfOpts    []rawkv.RawOption
+	// record audits the written keys; nil without auditing.
+	record batchAuditFunc
+}
+
+// run imports the request body, streaming the progress after each batch, and returns the final
+// progress.
+func (im *importer) run(w http.ResponseWriter, r *http.Request) types.ImportProgress {
+	var next func() (types.KeyValue, error)
+	if im.format == "csv" {
+		next = csvRecords(r.Body)
+	} else {
+		next = ndjsonRecords(r.Body)
+	}
+
+	rc := http.NewResponseController(w)
+	// HTTP/1 closes the request body once the response is flushed unless both may be used at the
+	// same time, and progress is flushed long before the whole body is read.
+	if err := rc.EnableFullDuplex(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("import: enabling full duplex: %v", err)
+	}
+	out := json.NewEncoder(w)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	var progress types.ImportProgress
+	fail := func(count int, msg string) {
+		progress.Failed += count
+		if len(progress.Errors) < maxImportErrors {
+			progress.Errors = append(progress.Errors, msg)
+		}
+	}
+
+	keys := make([][]byte, 0, im.batchSize)
+	values := make([][]byte, 0, im.batchSize)
+	flush := func() error {
+		if len(keys) == 0 {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), importIOTimeout)
+		defer cancel()
+
+		written, skipped, err := writeBatch(ctx, im.cli, keys, values, im.policy, im.cfOpts, im.record)
+		progress.Skipped += skipped
+		if err != nil {
+			fail(len(keys)-skipped, fmt.Sprintf("batch ending at record %d: %v", progress.Read, err))
+		} else {
+			progress.Written += written
+		}
+		keys, values = keys[:0], values[:0]
+
+		_ = rc.SetWriteDeadline(time.Now().Add(importIOTimeout))
+		if err := out.Encode(progress); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	for {
+		_ = rc.SetReadDeadline(time.Now().Add(importIOTimeout))
+		kv, err := next()
+		if err == io.EOF {
+			break
+		}
+		if r.Context().Err() != nil {
+			return progress
+		}
+		var recErr *recordError
+		if err != nil && !errors.As(err, &recErr) {
+			// The body itself could not be read, so nothing more can be imported. The record
+			// being read failed; how many followed it is unknown, which Truncated reports.
+			progress.Read++
+			progress.Truncated = true
+			fail(1, fmt.Sprintf("record %d: read error: %v", progress.Read, err))
+			break
+		}
+		progress.Read++
+		if err != nil {
+			fail(1, fmt.Sprintf("record %d: %v", progress.Read, err))
+			continue
+		}
+
+		key, err := utils.DecodeBytes(kv.Key, im.enc.KeyEncoding)
+		if err != nil || len(key) == 0 {
+			fail(1, fmt.Sprintf("record %d: invalid key", progress.Read))
+			continue
+		}
+		value, err := utils.DecodeBytes(kv.Value, im.enc.ValueEncoding)
+		if err != nil {
+			fail(1, fmt.Sprintf("record %d: invalid value: %v", progress.Read, err))
+			continue
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+		if len(keys) == im.batchSize {
+			if err := flush(); err != nil {
+				return progress
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return progress
+	}
+
+	progress.Done = true
+	_ = out.Encode(progress)
+	return progress
+}
+
+// writeBatch stores a batch with BatchPut. With the skip-existing policy, keys that already
+// exist are left untouched and reported as skipped. When record is set, the written keys are
+// audited with the values they replaced.
+func writeBatch(ctx context.Context, cli batchStore, keys, values [][]byte, policy string, opts []rawkv.RawOption, record batchAuditFunc) (written, skipped int, err error) {
+	var previous [][]byte
+	if policy == policySkipExisting || record != nil {
+		existing, err := cli.BatchGet(ctx, keys, opts...)
+		if err != nil {
+			if policy == policySkipExisting {
+				return 0, 0, err
+			}
+			// Reading the previous values for the audit log is best effort.
+			log.Printf("audit: failed to read previous values: %v", err)
+		} else {
+			previous = existing
+		}
+	}
+	if policy == policySkipExisting {
+		newKeys := make([][]byte, 0, len(keys))
+		newValues := make([][]byte, 0, len(values))
+		newPrevious := make([][]byte, 0, len(keys))
+		for i := range keys {
+			if previous[i] != nil {
+				skipped++
+				continue
+			}
+			newKeys = append(newKeys, keys[i])
+			newValues = append(newValues, values[i])
+			newPrevious = append(newPrevious, nil)
+		}
+		keys, values, previous = newKeys, newValues, newPrevious
+	}
+	if len(keys) == 0 {
+		return 0, skipped, nil
+	}
+	err = cli.BatchPut(ctx, keys, values, opts...)
+	if record != nil {
+		record(keys, previous, values, err)
+	}
+	if err != nil {
+		return 0, skipped, err
+	}
+	return len(keys), skipped, nil
+}
+
+// recordError marks a malformed record that can be skipped without aborting the import.
+type recordError struct {
+	err error
+}
+
+func (e *recordError) Error() string { return e.err.Error() }
+
+// ndjsonRecords reads {"key": ..., "value": ...} objects, one per line; blank lines are ignored.
+func ndjsonRecords(r io.Reader) func() (types.KeyValue, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	return func() (types.KeyValue, error) {
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if line == "" {
+				continue
+			}
+			var kv types.KeyValue
+			if err := json.Unmarshal([]byte(line), &kv); err != nil {
+				return kv, &recordError{errors.New("invalid JSON line")}
+			}
+			return kv, nil
+		}
+		if err := sc.Err(); err != nil {
+			return types.KeyValue{}, err
+		}
+		return types.KeyValue{}, io.EOF
+	}
+}
+
+// csvRecords reads key,value rows. A leading "key,value" header row is skipped.
+func csvRecords(r io.Reader) func() (types.KeyValue, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	first := true
+	return func() (types.KeyValue, error) {
+		for {
+			rec, err := cr.Read()
+			if err == io.EOF {
+				return types.KeyValue{}, io.EOF
+			}
+			if err != nil {
+				var parseErr *csv.ParseError
+				if errors.As(err, &parseErr) {
+					return types.KeyValue{}, &recordError{err}
+				}
+				return types.KeyValue{}, err
+			}
+			isHeader := first && len(rec) == 2 && strings.EqualFold(rec[0], "key") && strings.EqualFold(rec[1], "value")
+			first = false
+			if isHeader {
+				continue
+			}
+			if len(rec) != 2 {
+				return types.KeyValue{}, &recordError{fmt.Errorf("expected 2 columns, got %d", len(rec))}
+			}
+			return types.KeyValue{Key: rec[0], Value: rec[1]}, nil
+		}
+	}
+}
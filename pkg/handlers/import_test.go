@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/GetStream/tikv-ui/pkg/types"
+	"github.com/GetStream/tikv-ui/pkg/utils"
+	"github.com/tikv/client-go/v2/rawkv"
+)
+
+// fakeBatchStore is an in-memory batchStore.
+type fakeBatchStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (f *fakeBatchStore) BatchGet(ctx context.Context, keys [][]byte, options ...rawkv.RawOption) ([][]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([][]byte, len(keys))
+	for i, k := range keys {
+		out[i] = f.data[string(k)]
+	}
+	return out, nil
+}
+
+func (f *fakeBatchStore) BatchPut(ctx context.Context, keys, values [][]byte, options ...rawkv.RawOption) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, k := range keys {
+		f.data[string(k)] = values[i]
+	}
+	return nil
+}
+
+func TestImportStreamsSeveralBatches(t *testing.T) {
+	store := &fakeBatchStore{data: map[string][]byte{}}
+	im := &importer{
+		cli:       store,
+		format:    "ndjson",
+		enc:       types.Encoding{KeyEncoding: utils.EncodingUTF8, ValueEncoding: utils.EncodingUTF8},
+		policy:    policyOverwrite,
+		batchSize: defaultImportBatch,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		im.run(w, r)
+	}))
+	defer srv.Close()
+
+	// Large enough that the server has flushed progress long before it reads the last line.
+	const records = 2000
+	var body strings.Builder
+	for i := 0; i < records; i++ {
+		fmt.Fprintf(&body, `{"key":"key-%05d","value":"%s"}`+"\n", i, strings.Repeat("v", 100))
+	}
+
+	resp, err := http.Post(srv.URL, "application/x-ndjson", strings.NewReader(body.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var last types.ImportProgress
+	lines := 0
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		if err := json.Unmarshal(sc.Bytes(), &last); err != nil {
+			t.Fatalf("progress line %q: %v", sc.Text(), err)
+		}
+		lines++
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !last.Done || last.Read != records || last.Written != records || last.Failed != 0 || last.Truncated {
+		t.Errorf("summary = %+v, want all %d records written", last, records)
+	}
+	if want := (records+defaultImportBatch-1)/defaultImportBatch + 1; lines != want {
+		t.Errorf("got %d progress lines, want %d", lines, want)
+	}
+	if len(store.data) != records {
+		t.Errorf("store holds %d keys, want %d", len(store.data), records)
+	}
+}
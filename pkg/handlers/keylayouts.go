@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/GetStream/tikv-ui/pkg/server"
+	"github.com/GetStream/tikv-ui/pkg/utils"
+)
+
+// KeyLayouts lists the configured key layouts so clients can build structured keys
+func KeyLayouts(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			utils.MethodNotAllowed(w)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, map[string]any{"layouts": s.KeyLayouts.Layouts()})
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/GetStream/tikv-ui/pkg/server"
+	"github.com/GetStream/tikv-ui/pkg/types"
+	"github.com/GetStream/tikv-ui/pkg/utils"
+	"github.com/tikv/client-go/v2/rawkv"
+	"github.com/tikv/client-go/v2/txnkv"
+)
+
+// errKeyNotFound reports that the key to patch does not exist.
+var errKeyNotFound = errors.New("key not found")
+
+// patchError is a patch that could not be applied to the current value.
+type patchError struct{ error }
+
+// Patch applies a JSON Patch or merge patch to a stored JSON or msgpack value. The write is
+// conditional on the value read: raw clusters use CompareAndSwap and txn clusters a transaction,
+// so a concurrent change is reported with 409 instead of being overwritten.
+func Patch(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodPatch {
+			utils.MethodNotAllowed(w)
+			return
+		}
+
+		var req types.PatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		if len(req.Patch) == 0 {
+			utils.WriteError(w, http.StatusBadRequest, "patch is required")
+			return
+		}
+		if !validateEncoding(w, req.Encoding) {
+			return
+		}
+		key, ok := decodeKey(w, s.KeyLayouts, req.KeyInput, req.KeyEncoding)
+		if !ok {
+			return
+		}
+		cf, cfOpts, err := utils.ColumnFamily(req.CF)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		conn := s.GetActiveConnection()
+		if !checkWritable(w, conn) {
+			return
+		}
+		if !checkTxnOptions(w, conn, req.CF, 0) {
+			return
+		}
+		ifMatch := r.Header.Get("If-Match")
+
+		// patch computes the new value from the current one.
+		var previous []byte
+		patch := func(current []byte) ([]byte, error) {
+			previous = current
+			if current == nil {
+				return nil, errKeyNotFound
+			}
+			if ifMatch != "" && !utils.MatchETag(ifMatch, current) {
+				return nil, errValueChanged
+			}
+			value, err := utils.PatchValue(current, func(doc any) (any, error) {
+				return utils.ApplyPatch(doc, req.Patch, req.PatchType)
+			})
+			if err != nil {
+				return nil, patchError{err}
+			}
+			return value, nil
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		var value []byte
+		if conn.IsTxn() {
+			err = txnWrite(ctx, conn.TxnClient, key, "", func(txn *txnkv.KVTxn) error {
+				current, err := txnCurrent(ctx, txn, key)
+				if err != nil {
+					return err
+				}
+				if value, err = patch(current); err != nil {
+					return err
+				}
+				return txn.Set(key, value)
+			})
+		} else {
+			value, err = rawPatch(ctx, conn.Client, key, cfOpts, patch)
+		}
+
+		var perr patchError
+		switch {
+		case errors.As(err, &perr):
+			utils.WriteError(w, http.StatusBadRequest, "cannot apply patch: "+perr.Error())
+			return
+		case errors.Is(err, errKeyNotFound):
+			utils.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		case errors.Is(err, errValueChanged), errors.Is(err, errKeyHasTTL):
+			utils.WriteError(w, http.StatusConflict, err.Error())
+			return
+		case err != nil:
+			utils.WriteError(w, http.StatusInternalServerError, "TiKV error: "+err.Error())
+			return
+		}
+
+		recordWrite(r, s, cf, types.AuditRecord{Cluster: conn.Name, Operation: auditPatch, Key: key, PreviousValue: previous, NewValue: value, Details: auditDetails(cf)}, 0)
+
+		resp := newGetResponse(key, value, req.Encoding, s.KeyLayouts)
+		w.Header().Set("ETag", `"`+resp.ETag+`"`)
+		utils.WriteJSON(w, http.StatusOK, resp)
+	}
+}
+
+// rawPatch reads key, computes its new value with patch and writes it with CompareAndSwap against
+// the value read. Keys with a TTL are refused with errKeyHasTTL, since the swap would drop it.
+func rawPatch(ctx context.Context, cli *rawkv.Client, key []byte, cfOpts []rawkv.RawOption, patch func([]byte) ([]byte, error)) ([]byte, error) {
+	current, err := cli.Get(ctx, key, cfOpts...)
+	if err != nil {
+		return nil, err
+	}
+	value, err := patch(current)
+	if err != nil {
+		return nil, err
+	}
+	if keyTTL(ctx, cli, key, cfOpts) > 0 {
+		return nil, errKeyHasTTL
+	}
+	_, swapped, err := cli.CompareAndSwap(ctx, key, current, value, cfOpts...)
+	if err != nil {
+		return nil, err
+	}
+	if !swapped {
+		return nil, errValueChanged
+	}
+	return value, nil
+}
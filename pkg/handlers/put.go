@@ -3,12 +3,14 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/GetStream/tikv-ui/pkg/server"
 	"github.com/GetStream/tikv-ui/pkg/types"
 	"github.com/GetStream/tikv-ui/pkg/utils"
+	"github.com/tikv/client-go/v2/txnkv"
 )
 
 // Put handles PUT requests to store a key-value pair in TiKV
@@ -24,19 +26,118 @@ func Put(s *server.Server) http.HandlerFunc {
 			utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
 			return
 		}
-		if req.Key == "" {
-			utils.WriteError(w, http.StatusBadRequest, "key is required")
+		if !validateEncoding(w, req.Encoding) {
+			return
+		}
+		key, ok := decodeKey(w, s.KeyLayouts, req.KeyInput, req.KeyEncoding)
+		if !ok {
+			return
+		}
+		value, err := utils.EncodeValue(req.Value, req.ValueFormat, req.ValueEncoding, req.MsgpackEnvelope)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid value: "+err.Error())
+			return
+		}
+		cf, cfOpts, err := utils.ColumnFamily(req.CF)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		conn := s.GetActiveConnection()
+		if !checkWritable(w, conn) {
+			return
+		}
+		if !checkTxnOptions(w, conn, req.CF, 0) {
+			return
+		}
+		if conn.IsTxn() && req.TTLSeconds > 0 {
+			utils.WriteError(w, http.StatusBadRequest, "ttl_seconds is not supported for txn-mode clusters")
+			return
+		}
+
+		ifMatch := r.Header.Get("If-Match")
+		if ifMatch != "" && req.TTLSeconds > 0 {
+			utils.WriteError(w, http.StatusBadRequest, "ttl_seconds cannot be combined with If-Match")
 			return
 		}
 
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
 
-		if err := s.GetActiveClient().Put(ctx, []byte(req.Key), []byte(req.Value)); err != nil {
-			utils.WriteError(w, http.StatusInternalServerError, "TiKV Put error: "+err.Error())
-			return
+		// previous is only read when auditing or keeping history, except for conditional writes which need it anyway.
+		var previous []byte
+		var prevTTL uint64
+		cli := conn.Client
+		if conn.IsTxn() {
+			err := txnWrite(ctx, conn.TxnClient, key, ifMatch, func(txn *txnkv.KVTxn) error {
+				if trackPrevious(s) {
+					var err error
+					if previous, err = txnCurrent(ctx, txn, key); err != nil {
+						return err
+					}
+				}
+				return txn.Set(key, value)
+			})
+			if errors.Is(err, errValueChanged) {
+				utils.WriteError(w, http.StatusConflict, err.Error())
+				return
+			}
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "TiKV Commit error: "+err.Error())
+				return
+			}
+		} else if ifMatch != "" {
+			// Conditional writes go through CompareAndSwap so a concurrent change between
+			// the ETag check and the write is still detected.
+			current, err := cli.Get(ctx, key, cfOpts...)
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "TiKV Get error: "+err.Error())
+				return
+			}
+			if !utils.MatchETag(ifMatch, current) {
+				utils.WriteError(w, http.StatusConflict, "value has changed since it was read")
+				return
+			}
+			if keyTTL(ctx, cli, key, cfOpts) > 0 {
+				utils.WriteError(w, http.StatusConflict, errKeyHasTTL.Error())
+				return
+			}
+			_, swapped, err := cli.CompareAndSwap(ctx, key, current, value, cfOpts...)
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "TiKV CompareAndSwap error: "+err.Error())
+				return
+			}
+			if !swapped {
+				utils.WriteError(w, http.StatusConflict, "value has changed since it was read")
+				return
+			}
+			previous = current
+		} else {
+			if trackPrevious(s) {
+				if previous, err = cli.Get(ctx, key, cfOpts...); err != nil {
+					utils.WriteError(w, http.StatusInternalServerError, "TiKV Get error: "+err.Error())
+					return
+				}
+				prevTTL = previousTTL(ctx, s, cli, key, cfOpts)
+			}
+			if err := cli.PutWithTTL(ctx, key, value, req.TTLSeconds, cfOpts...); err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "TiKV Put error: "+err.Error())
+				return
+			}
+		}
+
+		rec := types.AuditRecord{Cluster: conn.Name, Operation: auditPut, Key: key, PreviousValue: previous, NewValue: value, Details: auditDetails(cf)}
+		if req.TTLSeconds > 0 {
+			if rec.Details == nil {
+				rec.Details = map[string]any{}
+			}
+			rec.Details["ttl_seconds"] = req.TTLSeconds
 		}
+		recordWrite(r, s, cf, rec, prevTTL)
 
-		utils.WriteJSON(w, http.StatusOK, map[string]bool{"ok": true})
+		etag := utils.ETag(value)
+		w.Header().Set("ETag", `"`+etag+`"`)
+		utils.WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "etag": etag, "cf": cf})
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/GetStream/tikv-ui/pkg/server"
@@ -12,6 +13,13 @@ import (
 	"github.com/tikv/client-go/v2/rawkv"
 )
 
+const (
+	// defaultScanBudget is the number of keys a filtered scan examines when no scan_budget is given.
+	defaultScanBudget = 10000
+	// maxScanBudget caps scan_budget so a single filtered scan cannot walk the whole cluster.
+	maxScanBudget = 200000
+)
+
 // Scan handles SCAN requests to retrieve a range of key-value pairs from TiKV
 func Scan(s *server.Server) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -25,29 +33,180 @@ func Scan(s *server.Server) http.HandlerFunc {
 			utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
 			return
 		}
-		if req.Limit <= 0 || req.Limit > rawkv.MaxRawKVScanLimit {
+		if !validateEncoding(w, req.Encoding) {
+			return
+		}
+		startKey, endKey, ok := decodeRange(w, s.KeyLayouts, req.KeyRange, req.KeyEncoding)
+		if !ok {
+			return
+		}
+		if req.Cursor != "" {
+			cursor, err := utils.DecodeCursor(req.Cursor)
+			if err != nil {
+				utils.WriteError(w, http.StatusBadRequest, "invalid cursor: "+err.Error())
+				return
+			}
+			if cursor.Reverse != req.Reverse {
+				utils.WriteError(w, http.StatusBadRequest, "cursor does not match scan direction")
+				return
+			}
+			if !cursor.Within(startKey, endKey) {
+				utils.WriteError(w, http.StatusBadRequest, "cursor is outside the requested range")
+				return
+			}
+			startKey, endKey = cursor.Resume(startKey, endKey)
+		}
+		if req.Reverse && len(endKey) == 0 {
+			utils.WriteError(w, http.StatusBadRequest, "end_key or a bounded prefix is required for reverse scans")
+			return
+		}
+		if req.Limit > rawkv.MaxRawKVScanLimit {
+			utils.WriteError(w, http.StatusBadRequest, "limit must not exceed "+strconv.Itoa(rawkv.MaxRawKVScanLimit))
+			return
+		}
+		if req.Limit <= 0 {
 			req.Limit = 100
 		}
+		if req.ValuePreviewBytes < 0 {
+			utils.WriteError(w, http.StatusBadRequest, "value_preview_bytes must not be negative")
+			return
+		}
+		cf, cfOpts, err := utils.ColumnFamily(req.CF)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		conn := s.GetActiveConnection()
+		if !checkTxnOptions(w, conn, req.CF, req.SnapshotTS) {
+			return
+		}
 
-		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		var filter *utils.Filter
+		timeout := 5 * time.Second
+		budget := req.Limit
+		if req.Filter != "" {
+			if req.KeysOnly {
+				utils.WriteError(w, http.StatusBadRequest, "filter cannot be combined with keys_only")
+				return
+			}
+			if filter, err = utils.ParseFilter(req.Filter); err != nil {
+				utils.WriteError(w, http.StatusBadRequest, "invalid filter: "+err.Error())
+				return
+			}
+			budget = req.ScanBudget
+			if budget <= 0 {
+				budget = defaultScanBudget
+			}
+			if budget > maxScanBudget {
+				utils.WriteError(w, http.StatusBadRequest, "scan_budget must not exceed "+strconv.Itoa(maxScanBudget))
+				return
+			}
+			timeout = 30 * time.Second
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
 		defer cancel()
 
-		keys, values, err := s.GetActiveClient().Scan(ctx, []byte(req.StartKey), []byte(req.EndKey), req.Limit)
-		if err != nil {
-			utils.WriteError(w, http.StatusInternalServerError, "TiKV Scan error: "+err.Error())
-			return
+		scan := rawScanner(conn.Client, cfOpts)
+		var snapshotTS uint64
+		if conn.IsTxn() {
+			snap, ts, err := txnSnapshot(ctx, conn.TxnClient, req.SnapshotTS)
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "TiKV snapshot error: "+err.Error())
+				return
+			}
+			scan, snapshotTS = txnScanner(snap), ts
+		}
+
+		items := make([]types.ScanItem, 0, req.Limit)
+		scanned := 0
+		exhausted := false
+		var last []byte
+
+		// Without a filter the budget equals the limit, so this is a single page. With a filter
+		// the range is walked page by page until enough items match or the budget runs out.
+	pages:
+		for len(items) < req.Limit && scanned < budget {
+			pageSize := min(budget-scanned, rawkv.MaxRawKVScanLimit)
+			if filter == nil {
+				pageSize = req.Limit - len(items)
+			}
+			pageStart, pageEnd := startKey, endKey
+			if last != nil {
+				pageStart, pageEnd = utils.ScanCursor{Key: last, Reverse: req.Reverse}.Resume(startKey, endKey)
+			}
+
+			keys, values, err := scan(ctx, pageStart, pageEnd, pageSize, req.Reverse, req.KeysOnly)
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "TiKV Scan error: "+err.Error())
+				return
+			}
+
+			for i := range keys {
+				scanned++
+				last = keys[i]
+				if req.KeysOnly {
+					items = append(items, newKeyItem(keys[i], req.Encoding, s.KeyLayouts))
+				} else if filter == nil {
+					items = append(items, newScanItem(keys[i], values[i], nil, req.Encoding, req.ValuePreviewBytes, s.KeyLayouts))
+				} else if parsed := parsedValue(values[i]); filter.Match(parsed) {
+					// The value parsed for the filter is reused for the item rather than decoded
+					// twice, unless the item shows ext values in full.
+					if req.MsgpackExt {
+						parsed = nil
+					}
+					items = append(items, newScanItem(keys[i], values[i], parsed, req.Encoding, req.ValuePreviewBytes, s.KeyLayouts))
+				}
+				if len(items) == req.Limit {
+					break pages
+				}
+			}
+			if len(keys) < pageSize {
+				exhausted = true
+				break
+			}
 		}
 
-		items := make([]types.ScanItem, 0, len(keys))
-		for i := range keys {
-			parsed, _ := utils.ParseValue(values[i])
-			items = append(items, types.ScanItem{
-				Key:      string(keys[i]),
-				Value:    parsed,
-				RawValue: utils.FormatRawValue(values[i]),
-			})
+		resp := types.ScanResponse{Items: items, CF: cf, Scanned: scanned, SnapshotTS: snapshotTS}
+		if !exhausted && last != nil {
+			cursor := utils.ScanCursor{Key: last, Reverse: req.Reverse}
+			// Probe for a single key past the page so has_more is exact rather than a guess.
+			probeStart, probeEnd := cursor.Resume(startKey, endKey)
+			more, _, err := scan(ctx, probeStart, probeEnd, 1, req.Reverse, true)
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "TiKV Scan error: "+err.Error())
+				return
+			}
+			if len(more) > 0 {
+				resp.HasMore = true
+				resp.NextCursor = utils.EncodeCursor(cursor)
+			}
 		}
 
-		utils.WriteJSON(w, http.StatusOK, types.ScanResponse{Items: items})
+		utils.WriteJSON(w, http.StatusOK, resp)
+	}
+}
+
+// parsedValue decodes a raw value for filter evaluation.
+func parsedValue(value []byte) any {
+	parsed, _ := utils.ParseValue(value)
+	return parsed
+}
+
+// pageScanner reads up to limit pairs from [startKey, endKey), walking downwards from endKey when reverse is set.
+type pageScanner func(ctx context.Context, startKey, endKey []byte, limit int, reverse, keysOnly bool) ([][]byte, [][]byte, error)
+
+// rawScanner scans a RawKV cluster with the given column family options.
+func rawScanner(cli *rawkv.Client, cfOpts []rawkv.RawOption) pageScanner {
+	return func(ctx context.Context, startKey, endKey []byte, limit int, reverse, keysOnly bool) ([][]byte, [][]byte, error) {
+		opts := cfOpts
+		if keysOnly {
+			opts = append(opts[:len(opts):len(opts)], rawkv.ScanKeyOnly())
+		}
+		if reverse {
+			// ReverseScan walks [endKey, startKey) downwards, so the bounds are swapped.
+			return cli.ReverseScan(ctx, endKey, startKey, limit, opts...)
+		}
+		return cli.Scan(ctx, startKey, endKey, limit, opts...)
 	}
 }
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/GetStream/tikv-ui/pkg/services"
+	"github.com/GetStream/tikv-ui/pkg/types"
+	"github.com/GetStream/tikv-ui/pkg/utils"
+)
+
+// ListTasks handles requests to list background tasks, or to fetch a single one with ?id=
+func ListTasks(tasks *services.TaskManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			utils.MethodNotAllowed(w)
+			return
+		}
+
+		if id := r.URL.Query().Get("id"); id != "" {
+			info, ok := tasks.Get(id)
+			if !ok {
+				utils.WriteError(w, http.StatusNotFound, "task not found")
+				return
+			}
+			utils.WriteJSON(w, http.StatusOK, info)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, types.TasksResponse{Tasks: tasks.List()})
+	}
+}
+
+// CancelTask handles requests to cancel a running background task
+func CancelTask(tasks *services.TaskManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			utils.MethodNotAllowed(w)
+			return
+		}
+
+		var req struct {
+			ID string `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		if req.ID == "" {
+			utils.WriteError(w, http.StatusBadRequest, "id is required")
+			return
+		}
+
+		if !tasks.Cancel(req.ID) {
+			utils.WriteError(w, http.StatusNotFound, "task not found")
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, map[string]bool{"ok": true})
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/GetStream/tikv-ui/pkg/server"
+	"github.com/GetStream/tikv-ui/pkg/types"
+	"github.com/GetStream/tikv-ui/pkg/utils"
+	"github.com/tikv/client-go/v2/rawkv"
+)
+
+// errKeyHasTTL refuses a conditional raw write on a key with a TTL: CompareAndSwap takes no TTL,
+// so the write would silently make the key permanent.
+var errKeyHasTTL = errors.New("key has a TTL, which a compare-and-swap would drop; write it with put and ttl_seconds instead")
+
+// keyTTL returns the time to live key has left, or 0 when it has none or does not exist. Clusters
+// without TTL support reject GetKeyTTL and count as having none.
+func keyTTL(ctx context.Context, cli *rawkv.Client, key []byte, cfOpts []rawkv.RawOption) uint64 {
+	ttl, err := cli.GetKeyTTL(ctx, key, cfOpts...)
+	if err != nil || ttl == nil {
+		return 0
+	}
+	return *ttl
+}
+
+// SetTTL handles requests to change the TTL of an existing key; a TTL of 0 removes the expiry.
+// client-go v2.0.7 has neither a SetTTL call nor a CompareAndSwap that takes a TTL, so the current
+// value is read and written back with PutWithTTL. That read-modify-write is not atomic: a write
+// landing between the Get and the Put is overwritten with the value read. Sending If-Match with
+// the ETag of the value the caller expects at least refuses the change, with 409, when the key
+// no longer holds that value.
+func SetTTL(s *server.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			utils.MethodNotAllowed(w)
+			return
+		}
+
+		var req types.TTLRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		if req.Key == "" {
+			utils.WriteError(w, http.StatusBadRequest, "key is required")
+			return
+		}
+		if !validateEncoding(w, req.Encoding) {
+			return
+		}
+		key, ok := decodeField(w, "key", req.Key, req.KeyEncoding)
+		if !ok {
+			return
+		}
+
+		conn, ok := activeRawConnection(w, s)
+		if !ok || !checkWritable(w, conn) {
+			return
+		}
+		cli := conn.Client
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		val, err := cli.Get(ctx, key)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "TiKV Get error: "+err.Error())
+			return
+		}
+		if val == nil {
+			utils.WriteError(w, http.StatusNotFound, "key not found")
+			return
+		}
+		if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && !utils.MatchETag(ifMatch, val) {
+			utils.WriteError(w, http.StatusConflict, errValueChanged.Error())
+			return
+		}
+
+		if err := cli.PutWithTTL(ctx, key, val, req.TTLSeconds); err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "TiKV Put error: "+err.Error())
+			return
+		}
+
+		recordAudit(r, s, types.AuditRecord{
+			Cluster:       conn.Name,
+			Operation:     auditTTL,
+			Key:           key,
+			PreviousValue: val,
+			NewValue:      val,
+			Details:       map[string]any{"ttl_seconds": req.TTLSeconds},
+		})
+
+		utils.WriteJSON(w, http.StatusOK, map[string]any{"ok": true, "ttl_seconds": req.TTLSeconds})
+	}
+}
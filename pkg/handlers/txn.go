@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/GetStream/tikv-ui/pkg/server"
+	"github.com/GetStream/tikv-ui/pkg/utils"
+	tikverr "github.com/tikv/client-go/v2/error"
+	"github.com/tikv/client-go/v2/rawkv"
+	"github.com/tikv/client-go/v2/txnkv"
+	"github.com/tikv/client-go/v2/txnkv/txnsnapshot"
+)
+
+// errValueChanged reports that a conditional write found a different value than the client last read.
+var errValueChanged = errors.New("value has changed since it was read")
+
+// activeRawClient returns the active cluster's RawKV client, writing a 400 response when the
+// cluster is in txn mode and the endpoint has no transactional counterpart.
+func activeRawClient(w http.ResponseWriter, s *server.Server) (*rawkv.Client, bool) {
+	conn, ok := activeRawConnection(w, s)
+	if !ok {
+		return nil, false
+	}
+	return conn.Client, true
+}
+
+// activeRawConnection is activeRawClient for handlers that also need the cluster's name.
+func activeRawConnection(w http.ResponseWriter, s *server.Server) (*server.ClusterConnection, bool) {
+	conn := s.GetActiveConnection()
+	if conn.IsTxn() {
+		utils.WriteError(w, http.StatusBadRequest, "not supported for txn-mode cluster "+conn.Name)
+		return nil, false
+	}
+	return conn, true
+}
+
+// rawCluster looks up a registered cluster for a cross-cluster operation, writing a 404 response
+// when it is unknown and a 400 response when it is in txn mode.
+func rawCluster(w http.ResponseWriter, s *server.Server, name string) (*server.ClusterConnection, bool) {
+	conn, ok := s.GetCluster(name)
+	if !ok {
+		utils.WriteError(w, http.StatusNotFound, "cluster '"+name+"' not found")
+		return nil, false
+	}
+	if conn.IsTxn() {
+		utils.WriteError(w, http.StatusBadRequest, "not supported for txn-mode cluster "+name)
+		return nil, false
+	}
+	return conn, true
+}
+
+// txnSnapshot returns a read-only snapshot at ts, or at a fresh timestamp from PD when ts is zero.
+func txnSnapshot(ctx context.Context, cli *txnkv.Client, ts uint64) (*txnsnapshot.KVSnapshot, uint64, error) {
+	if ts == 0 {
+		var err error
+		if ts, err = cli.GetTimestamp(ctx); err != nil {
+			return nil, 0, err
+		}
+	}
+	return cli.GetSnapshot(ts), ts, nil
+}
+
+// txnGet reads a key from a snapshot; a nil value means the key was not found.
+func txnGet(ctx context.Context, snap *txnsnapshot.KVSnapshot, key []byte) ([]byte, error) {
+	val, err := snap.Get(ctx, key)
+	if tikverr.IsErrNotFound(err) {
+		return nil, nil
+	}
+	return val, err
+}
+
+// txnWrite applies mutate to key in a transaction of its own. With ifMatch set the current value is
+// read and checked inside the transaction first, so a concurrent commit surfaces as a write
+// conflict and is reported as errValueChanged.
+func txnWrite(ctx context.Context, cli *txnkv.Client, key []byte, ifMatch string, mutate func(*txnkv.KVTxn) error) error {
+	txn, err := cli.Begin()
+	if err != nil {
+		return err
+	}
+
+	if ifMatch != "" {
+		current, err := txnCurrent(ctx, txn, key)
+		if err != nil {
+			txn.Rollback()
+			return err
+		}
+		if !utils.MatchETag(ifMatch, current) {
+			txn.Rollback()
+			return errValueChanged
+		}
+	}
+
+	if err := mutate(txn); err != nil {
+		txn.Rollback()
+		return err
+	}
+	if err := txn.Commit(ctx); err != nil {
+		if tikverr.IsErrWriteConflict(err) {
+			return errValueChanged
+		}
+		return err
+	}
+	return nil
+}
+
+// txnCurrent reads key inside txn; a nil value means the key was not found.
+func txnCurrent(ctx context.Context, txn *txnkv.KVTxn, key []byte) ([]byte, error) {
+	val, err := txn.Get(ctx, key)
+	if tikverr.IsErrNotFound(err) {
+		return nil, nil
+	}
+	return val, err
+}
+
+// snapshotIterator is the iterator returned by snapshot scans.
+type snapshotIterator interface {
+	Valid() bool
+	Key() []byte
+	Value() []byte
+	Next() error
+	Close()
+}
+
+// txnScanner walks a snapshot with its iterators, so txn-mode scans share the paging logic of raw
+// scans. The iterators take no context, so the walk checks ctx itself and stops once it is done.
+func txnScanner(snap *txnsnapshot.KVSnapshot) pageScanner {
+	return func(ctx context.Context, startKey, endKey []byte, limit int, reverse, keysOnly bool) ([][]byte, [][]byte, error) {
+		snap.SetKeyOnly(keysOnly)
+
+		var it snapshotIterator
+		var err error
+		if reverse {
+			// IterReverse yields keys below endKey, so the lower bound is checked by hand.
+			it, err = snap.IterReverse(endKey)
+		} else {
+			it, err = snap.Iter(startKey, endKey)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		defer it.Close()
+
+		var keys, values [][]byte
+		for it.Valid() && len(keys) < limit {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, err
+			}
+			if reverse && bytes.Compare(it.Key(), startKey) < 0 {
+				break
+			}
+			keys = append(keys, bytes.Clone(it.Key()))
+			values = append(values, bytes.Clone(it.Value()))
+			if err := it.Next(); err != nil {
+				return nil, nil, err
+			}
+		}
+		return keys, values, nil
+	}
+}
+
+// checkTxnOptions rejects options that only apply to the other cluster mode: column families are
+// a RawKV concept and snapshot timestamps only exist for transactional reads.
+func checkTxnOptions(w http.ResponseWriter, conn *server.ClusterConnection, cf string, snapshotTS uint64) bool {
+	if conn.IsTxn() && cf != "" && cf != utils.CFDefault {
+		utils.WriteError(w, http.StatusBadRequest, "cf is not supported for txn-mode clusters")
+		return false
+	}
+	if !conn.IsTxn() && snapshotTS != 0 {
+		utils.WriteError(w, http.StatusBadRequest, "snapshot_ts is only supported for txn-mode clusters")
+		return false
+	}
+	return true
+}
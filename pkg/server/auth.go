@@ -0,0 +1,211 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GetStream/tikv-ui/pkg/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// errNoCredentials is returned by providers when the request carries no credentials they handle.
+var errNoCredentials = errors.New("no credentials")
+
+// AuthProvider authenticates requests with one kind of credential.
+type AuthProvider interface {
+	// Authenticate returns the caller's identity. It returns errNoCredentials when the request
+	// has no credentials of its kind, and another error when they are present but invalid.
+	Authenticate(r *http.Request) (string, error)
+	// Challenge is the WWW-Authenticate header sent with 401 responses.
+	Challenge() string
+}
+
+// Auth requires API requests to be authenticated by one of its providers and stores the
+// caller's identity in the request context (see CallerIdentity). Only paths under /api/ are
+// protected; the health check and the frontend's static files stay public.
+type Auth struct {
+	providers []AuthProvider
+}
+
+func NewAuth(providers ...AuthProvider) *Auth {
+	return &Auth{providers: providers}
+}
+
+// Middleware rejects unauthenticated API requests with 401.
+func (a *Auth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var failure error
+		for _, p := range a.providers {
+			identity, err := p.Authenticate(r)
+			if err == nil {
+				next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+				return
+			}
+			if failure == nil && !errors.Is(err, errNoCredentials) {
+				failure = err
+			}
+		}
+
+		msg := "authentication required"
+		if failure != nil {
+			msg = "authentication failed: " + failure.Error()
+		}
+		log.Printf("%s %s from %s: %s", r.Method, r.URL.Path, ClientIP(r), msg)
+		for _, p := range a.providers {
+			w.Header().Add("WWW-Authenticate", p.Challenge())
+		}
+		utils.WriteError(w, http.StatusUnauthorized, msg)
+	})
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// TokenAuth accepts static API tokens sent as "Authorization: Bearer <token>".
+type TokenAuth struct {
+	// tokens maps the SHA-256 of each token to its identity, so lookups compare fixed-size digests.
+	tokens map[[sha256.Size]byte]string
+}
+
+// NewTokenAuth parses "identity:token" pairs separated by commas, e.g. "ci:s3cret,alice:t0ken".
+func NewTokenAuth(spec string) (*TokenAuth, error) {
+	t := &TokenAuth{tokens: make(map[[sha256.Size]byte]string)}
+	for _, pair := range utils.SplitAndTrim(spec, ",") {
+		identity, token, ok := strings.Cut(pair, ":")
+		if !ok || identity == "" || token == "" {
+			return nil, fmt.Errorf("invalid token entry %q, expected identity:token", pair)
+		}
+		t.tokens[sha256.Sum256([]byte(token))] = identity
+	}
+	if len(t.tokens) == 0 {
+		return nil, errors.New("no tokens configured")
+	}
+	return t, nil
+}
+
+func (t *TokenAuth) Authenticate(r *http.Request) (string, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return "", errNoCredentials
+	}
+	sum := sha256.Sum256([]byte(token))
+	for known, identity := range t.tokens {
+		if subtle.ConstantTimeCompare(sum[:], known[:]) == 1 {
+			return identity, nil
+		}
+	}
+	// JWTs are left to the OIDC provider.
+	if strings.Count(token, ".") == 2 {
+		return "", errNoCredentials
+	}
+	return "", errors.New("invalid token")
+}
+
+func (t *TokenAuth) Challenge() string {
+	return `Bearer realm="tikv-ui"`
+}
+
+// basicAuthCacheTTL is how long a verified password is remembered, sparing a bcrypt
+// comparison on every request of a session.
+const basicAuthCacheTTL = 5 * time.Minute
+
+// BasicAuth accepts HTTP basic credentials checked against bcrypt password hashes.
+type BasicAuth struct {
+	users map[string][]byte
+
+	mu       sync.Mutex
+	verified map[[sha256.Size]byte]time.Time
+}
+
+// LoadBasicAuth reads an htpasswd-style file of "user:bcrypt-hash" lines, as written by
+// `htpasswd -B`. Blank lines and lines starting with # are ignored.
+func LoadBasicAuth(path string) (*BasicAuth, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b := &BasicAuth{users: make(map[string][]byte), verified: make(map[[sha256.Size]byte]time.Time)}
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		user, hash, ok := strings.Cut(text, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("line %d: expected user:hash", line)
+		}
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("line %d: user %q does not have a bcrypt hash", line, user)
+		}
+		b.users[user] = []byte(hash)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(b.users) == 0 {
+		return nil, errors.New("no users configured")
+	}
+	return b, nil
+}
+
+func (b *BasicAuth) Authenticate(r *http.Request) (string, error) {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return "", errNoCredentials
+	}
+	hash, known := b.users[user]
+	if !known {
+		return "", errors.New("invalid username or password")
+	}
+
+	// The cache key covers the stored hash too, so changing a password invalidates it.
+	key := sha256.Sum256([]byte(user + "\x00" + password + "\x00" + string(hash)))
+	b.mu.Lock()
+	expires, cached := b.verified[key]
+	b.mu.Unlock()
+	if cached && time.Now().Before(expires) {
+		return user, nil
+	}
+
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil {
+		return "", errors.New("invalid username or password")
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	for k, exp := range b.verified {
+		if now.After(exp) {
+			delete(b.verified, k)
+		}
+	}
+	b.verified[key] = now.Add(basicAuthCacheTTL)
+	b.mu.Unlock()
+	return user, nil
+}
+
+func (b *BasicAuth) Challenge() string {
+	return `Basic realm="tikv-ui", charset="UTF-8"`
+}
@@ -0,0 +1,389 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"maps"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testIssuer is a local OIDC issuer serving discovery and a JWKS with one RSA and one EC key.
+type testIssuer struct {
+	*httptest.Server
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+	// hang makes the key set endpoint block until the test ends, like an unresponsive issuer.
+	hang    atomic.Bool
+	release chan struct{}
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iss := &testIssuer{rsaKey: rsaKey, ecKey: ecKey, release: make(chan struct{})}
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": iss.URL, "jwks_uri": iss.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		if iss.hang.Load() {
+			<-iss.release
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+		}})
+	})
+	iss.Server = httptest.NewServer(mux)
+	t.Cleanup(iss.Close)
+	t.Cleanup(func() { close(iss.release) })
+	return iss
+}
+
+// sign returns a JWT for claims, signed with the issuer's key of the given ID.
+func (iss *testIssuer) sign(t *testing.T, kid string, claims map[string]any) string {
+	t.Helper()
+	alg := "RS256"
+	if kid == "ec" {
+		alg = "ES256"
+	}
+	return iss.signAs(t, kid, alg, claims)
+}
+
+// signAs is sign with the algorithm named in the header; its hash is used for the digest.
+func (iss *testIssuer) signAs(t *testing.T, kid, alg string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hash, err := jwtHash(alg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := hash.New()
+	h.Write([]byte(input))
+	digest := h.Sum(nil)
+
+	var sig []byte
+	if kid == "ec" {
+		r, s, err := ecdsa.Sign(rand.Reader, iss.ecKey, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	} else {
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, iss.rsaKey, hash, digest); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func bearerRequest(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/api/clusters", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func TestOIDCAuth(t *testing.T) {
+	iss := newTestIssuer(t)
+	auth, err := NewOIDCAuth(OIDCConfig{Issuer: iss.URL, Audience: "tikv-ui"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Unix()
+	valid := map[string]any{"iss": iss.URL, "aud": "tikv-ui", "sub": "u1", "email": "alice@example.com", "exp": now + 300}
+	with := func(k string, v any) map[string]any {
+		c := map[string]any{}
+		for key, val := range valid {
+			c[key] = val
+		}
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{"rsa", iss.sign(t, "rsa", valid), "alice@example.com"},
+		{"ec", iss.sign(t, "ec", valid), "alice@example.com"},
+		{"audience list", iss.sign(t, "rsa", with("aud", []string{"other", "tikv-ui"})), "alice@example.com"},
+		{"sub fallback", iss.sign(t, "rsa", with("email", nil)), "u1"},
+		{"expired", iss.sign(t, "rsa", with("exp", now-600)), ""},
+		{"no exp", iss.sign(t, "rsa", with("exp", nil)), ""},
+		{"not yet valid", iss.sign(t, "rsa", with("nbf", now+600)), ""},
+		{"wrong audience", iss.sign(t, "rsa", with("aud", "other")), ""},
+		{"no audience", iss.sign(t, "rsa", with("aud", nil)), ""},
+		{"no issuer", iss.sign(t, "rsa", with("iss", nil)), ""},
+		{"wrong issuer", iss.sign(t, "rsa", with("iss", "https://evil.example.com")), ""},
+		{"unknown key", iss.sign(t, "missing", valid), ""},
+		{"rs384", iss.signAs(t, "rsa", "RS384", valid), "alice@example.com"},
+		{"curve mismatch", iss.signAs(t, "ec", "ES512", valid), ""},
+		{"rsa alg on ec key", iss.signAs(t, "ec", "RS256", valid), ""},
+		{"ec alg on rsa key", iss.signAs(t, "rsa", "ES256", valid), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := auth.Authenticate(bearerRequest(tt.token))
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("expected an error, got identity %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("identity = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("bad signature", func(t *testing.T) {
+		other := iss.sign(t, "rsa", with("sub", "u2"))
+		token := iss.sign(t, "rsa", valid)
+		// Pair the claims of one token with the signature of another.
+		forged := token[:len(token)-len(sigPart(token))] + sigPart(other)
+		if _, err := auth.Authenticate(bearerRequest(forged)); err == nil {
+			t.Fatal("expected a signature error")
+		}
+	})
+
+	t.Run("alg none", func(t *testing.T) {
+		header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"rsa"}`))
+		payload, _ := json.Marshal(valid)
+		token := header + "." + base64.RawURLEncoding.EncodeToString(payload) + "."
+		if _, err := auth.Authenticate(bearerRequest(token)); err == nil {
+			t.Fatal("expected unsigned token to be rejected")
+		}
+	})
+}
+
+func TestNewOIDCAuthRequiresIssuerAndAudience(t *testing.T) {
+	for _, cfg := range []OIDCConfig{
+		{Issuer: "https://issuer.example.com"},
+		{JWKSURL: "https://issuer.example.com/keys", Audience: "tikv-ui"},
+	} {
+		if _, err := NewOIDCAuth(cfg); err == nil {
+			t.Errorf("NewOIDCAuth(%+v) accepted a configuration that trusts any client of the issuer", cfg)
+		}
+	}
+}
+
+// TestOIDCAuthJWKSURL checks the claims when the keys come from a JWKS URL rather than discovery.
+func TestOIDCAuthJWKSURL(t *testing.T) {
+	iss := newTestIssuer(t)
+	auth, err := NewOIDCAuth(OIDCConfig{Issuer: "https://issuer.example.com", Audience: "tikv-ui", JWKSURL: iss.URL + "/keys"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := map[string]any{"iss": "https://issuer.example.com", "aud": "tikv-ui", "sub": "u1", "exp": time.Now().Unix() + 300}
+	if _, err := auth.Authenticate(bearerRequest(iss.sign(t, "rsa", valid))); err != nil {
+		t.Fatal(err)
+	}
+	for claim, value := range map[string]string{"iss": iss.URL, "aud": "other-client"} {
+		claims := maps.Clone(valid)
+		claims[claim] = value
+		if _, err := auth.Authenticate(bearerRequest(iss.sign(t, "rsa", claims))); err == nil {
+			t.Errorf("token with %s %q was accepted", claim, value)
+		}
+	}
+}
+
+func TestOIDCAuthSlowIssuer(t *testing.T) {
+	iss := newTestIssuer(t)
+	auth, _ := NewOIDCAuth(OIDCConfig{Issuer: iss.URL, Audience: "tikv-ui"})
+	token := iss.sign(t, "rsa", map[string]any{"iss": iss.URL, "aud": "tikv-ui", "sub": "u1", "exp": time.Now().Unix() + 300})
+	if _, err := auth.Authenticate(bearerRequest(token)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once the key set is stale, the refresh hangs; known keys must keep working meanwhile.
+	iss.hang.Store(true)
+	auth.mu.Lock()
+	auth.fetchedAt = time.Now().Add(-2 * jwksRefreshInterval)
+	auth.mu.Unlock()
+
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := auth.Authenticate(bearerRequest(token))
+			done <- err
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("a cached key waited for the key set refresh")
+		}
+	}
+}
+
+func sigPart(token string) string {
+	for i := len(token) - 1; i >= 0; i-- {
+		if token[i] == '.' {
+			return token[i+1:]
+		}
+	}
+	return ""
+}
+
+func TestTokenAuth(t *testing.T) {
+	auth, err := NewTokenAuth("ci:s3cret, alice:t0ken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := auth.Authenticate(bearerRequest("t0ken")); err != nil || got != "alice" {
+		t.Errorf("Authenticate = %q, %v, want alice", got, err)
+	}
+	if _, err := auth.Authenticate(bearerRequest("wrong")); err == nil {
+		t.Error("expected an invalid token error")
+	}
+	if _, err := auth.Authenticate(httptest.NewRequest(http.MethodGet, "/api/clusters", nil)); err != errNoCredentials {
+		t.Errorf("err = %v, want errNoCredentials", err)
+	}
+	if _, err := NewTokenAuth("missing-token"); err == nil {
+		t.Error("expected an error for an entry without a token")
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "users")
+	if err := os.WriteFile(path, []byte("# users\nalice:"+string(hash)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	auth, err := LoadBasicAuth(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(user, password string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/api/clusters", nil)
+		r.SetBasicAuth(user, password)
+		return r
+	}
+	for i := 0; i < 2; i++ { // the second round is served from the cache
+		if got, err := auth.Authenticate(request("alice", "hunter2")); err != nil || got != "alice" {
+			t.Fatalf("Authenticate = %q, %v, want alice", got, err)
+		}
+		if _, err := auth.Authenticate(request("alice", "wrong")); err == nil {
+			t.Fatal("expected a wrong password to be rejected")
+		}
+	}
+	if _, err := auth.Authenticate(request("bob", "hunter2")); err == nil {
+		t.Error("expected an unknown user to be rejected")
+	}
+
+	if err := os.WriteFile(path, []byte("alice:plaintext\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadBasicAuth(path); err == nil {
+		t.Error("expected a non-bcrypt entry to be refused")
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	iss := newTestIssuer(t)
+	tokens, _ := NewTokenAuth("ci:s3cret")
+	oidc, _ := NewOIDCAuth(OIDCConfig{Issuer: iss.URL, Audience: "tikv-ui"})
+	handler := NewAuth(tokens, oidc).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(CallerIdentity(r.Context())))
+	}))
+
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec
+	}
+
+	rec := serve(httptest.NewRequest(http.MethodGet, "/api/clusters", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rec.Code)
+	}
+	if got := rec.Header().Values("WWW-Authenticate"); len(got) != 2 {
+		t.Errorf("WWW-Authenticate = %q, want one challenge per provider", got)
+	}
+
+	if rec := serve(bearerRequest("s3cret")); rec.Code != http.StatusOK || rec.Body.String() != "ci" {
+		t.Errorf("static token: %d %q", rec.Code, rec.Body.String())
+	}
+
+	jwt := iss.sign(t, "rsa", map[string]any{"iss": iss.URL, "aud": "tikv-ui", "sub": "svc", "exp": time.Now().Unix() + 60})
+	if rec := serve(bearerRequest(jwt)); rec.Code != http.StatusOK || rec.Body.String() != "svc" {
+		t.Errorf("jwt: %d %q", rec.Code, rec.Body.String())
+	}
+
+	if rec := serve(bearerRequest("nope")); rec.Code != http.StatusUnauthorized {
+		t.Errorf("invalid token: status = %d, want 401", rec.Code)
+	}
+
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/health", nil)); rec.Code != http.StatusOK {
+		t.Errorf("public path: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	request := func(origin string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/api/clusters", nil)
+		r.Header.Set("Origin", origin)
+		return r
+	}
+
+	rec := httptest.NewRecorder()
+	CORSMiddleware(nil, next).ServeHTTP(rec, request("https://a.example.com"))
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" || rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("open CORS headers = %v", rec.Header())
+	}
+
+	handler := CORSMiddleware([]string{"https://ui.example.com"}, next)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, request("https://ui.example.com"))
+	if rec.Header().Get("Access-Control-Allow-Origin") != "https://ui.example.com" || rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Errorf("allowed origin headers = %v", rec.Header())
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, request("https://evil.example.com"))
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("other origin was allowed: %v", rec.Header())
+	}
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying the authenticated caller's identity.
+func WithIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// CallerIdentity returns the identity stored by WithIdentity, or "" for anonymous requests.
+func CallerIdentity(ctx context.Context) string {
+	identity, _ := ctx.Value(identityKey{}).(string)
+	return identity
+}
+
+// ClientIP returns the address of the peer that sent the request. X-Forwarded-For is not
+// trusted here since any client can set it; callers that want it record it separately.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
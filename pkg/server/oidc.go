@@ -0,0 +1,455 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksRefreshInterval is how often the signing keys are refetched.
+	jwksRefreshInterval = time.Hour
+	// jwksMinRefetch limits refetches triggered by tokens signed with an unknown key.
+	jwksMinRefetch = time.Minute
+	// jwtLeeway absorbs clock skew between the issuer and this server.
+	jwtLeeway = time.Minute
+)
+
+// OIDCConfig configures JWT verification.
+type OIDCConfig struct {
+	// Issuer is the expected iss claim; it is also where the JWKS URL is discovered from.
+	Issuer string
+	// Audience must be one of the token's aud values, so tokens the issuer grants other clients
+	// are refused.
+	Audience string
+	// JWKSURL overrides discovery through the issuer's /.well-known/openid-configuration.
+	JWKSURL string
+	// IdentityClaim names the claim used as the caller's identity; by default email, then sub.
+	IdentityClaim string
+	// HTTPClient fetches the discovery document and keys; http.DefaultClient when nil.
+	HTTPClient *http.Client
+}
+
+// OIDCAuth accepts JWTs sent as "Authorization: Bearer <jwt>", verified against the issuer's
+// JSON Web Key Set. RS256/384/512 and ES256/384/512 signatures are supported.
+type OIDCAuth struct {
+	cfg OIDCConfig
+
+	mu        sync.Mutex
+	jwksURL   string
+	keys      map[string]signingKey
+	fetchedAt time.Time
+	// fetching is closed when the key set fetch in flight completes; nil when none is running.
+	fetching chan struct{}
+}
+
+// signingKey is a public key of the key set with the algorithms it may verify.
+type signingKey struct {
+	key crypto.PublicKey
+	// alg is the only algorithm the key verifies; for RSA keys without an alg in the key set it
+	// is empty and any RS algorithm is accepted.
+	alg string
+}
+
+func (k signingKey) allows(alg string) bool {
+	if k.alg != "" {
+		return alg == k.alg
+	}
+	_, isRSA := k.key.(*rsa.PublicKey)
+	return isRSA && strings.HasPrefix(alg, "RS")
+}
+
+func NewOIDCAuth(cfg OIDCConfig) (*OIDCAuth, error) {
+	// An issuer's keys sign tokens for every client it serves, so both claims have to be checked
+	// to accept only the tokens meant for this server.
+	if cfg.Issuer == "" {
+		return nil, errors.New("an issuer is required")
+	}
+	if cfg.Audience == "" {
+		return nil, errors.New("an audience is required")
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &OIDCAuth{cfg: cfg, jwksURL: cfg.JWKSURL}, nil
+}
+
+func (o *OIDCAuth) Challenge() string {
+	return `Bearer realm="tikv-ui"`
+}
+
+func (o *OIDCAuth) Authenticate(r *http.Request) (string, error) {
+	token, ok := bearerToken(r)
+	if !ok || strings.Count(token, ".") != 2 {
+		return "", errNoCredentials
+	}
+	claims, err := o.verify(r.Context(), token)
+	if err != nil {
+		return "", fmt.Errorf("invalid token: %w", err)
+	}
+	return o.identity(claims)
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// verify checks the token's signature and standard claims and returns its claims.
+func (o *OIDCAuth) verify(ctx context.Context, token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	var header jwtHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("bad header: %w", err)
+	}
+	hash, err := jwtHash(header.Alg)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("bad signature encoding")
+	}
+
+	key, err := o.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	// Binding each key to one algorithm stops a token from choosing how its signature is checked.
+	if !key.allows(header.Alg) {
+		return nil, fmt.Errorf("algorithm %s does not match key %q", header.Alg, header.Kid)
+	}
+	h := hash.New()
+	h.Write([]byte(parts[0] + "." + parts[1]))
+	if err := verifySignature(key.key, hash, h.Sum(nil), sig); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("bad claims: %w", err)
+	}
+	if err := o.checkClaims(claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func decodeJWTPart(part string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// jwtHash returns the hash of a supported algorithm. HMAC and "none" are refused: the keys come
+// from the issuer, so only asymmetric signatures make sense.
+func jwtHash(alg string) (crypto.Hash, error) {
+	switch alg {
+	case "RS256", "ES256":
+		return crypto.SHA256, nil
+	case "RS384", "ES384":
+		return crypto.SHA384, nil
+	case "RS512", "ES512":
+		return crypto.SHA512, nil
+	}
+	return 0, fmt.Errorf("unsupported algorithm %q", alg)
+}
+
+func verifySignature(key crypto.PublicKey, hash crypto.Hash, digest, sig []byte) error {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(k, hash, digest, sig); err != nil {
+			return errors.New("signature verification failed")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		// JWS encodes ECDSA signatures as the fixed-size concatenation of r and s.
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return errors.New("signature verification failed")
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return errors.New("signature verification failed")
+		}
+		return nil
+	}
+	return errors.New("unsupported key type")
+}
+
+func (o *OIDCAuth) checkClaims(claims map[string]any, now time.Time) error {
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("missing exp claim")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(jwtLeeway)) {
+		return errors.New("token has expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(jwtLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token is not valid yet")
+	}
+	if claims["iss"] != o.cfg.Issuer {
+		return fmt.Errorf("unexpected issuer %v", claims["iss"])
+	}
+	if !hasAudience(claims["aud"], o.cfg.Audience) {
+		return errors.New("token is not intended for this audience")
+	}
+	return nil
+}
+
+// hasAudience reports whether the aud claim, a string or a list of strings, contains want.
+func hasAudience(aud any, want string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == want
+	case []any:
+		for _, v := range a {
+			if v == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (o *OIDCAuth) identity(claims map[string]any) (string, error) {
+	names := []string{"email", "sub"}
+	if o.cfg.IdentityClaim != "" {
+		names = []string{o.cfg.IdentityClaim}
+	}
+	for _, name := range names {
+		if v, ok := claims[name].(string); ok && v != "" {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("token has no %s claim", strings.Join(names, " or "))
+}
+
+// key returns the signing key with the given ID. Unknown IDs wait for a fetch of the key set,
+// rate limited to one per jwksMinRefetch; a stale set is refreshed in the background while its keys
+// stay in use. Fetches run outside o.mu, so a slow issuer never holds up tokens with known keys.
+func (o *OIDCAuth) key(ctx context.Context, kid string) (signingKey, error) {
+	o.mu.Lock()
+	key, ok := o.lookup(kid)
+	sinceFetch := time.Since(o.fetchedAt)
+	if ok {
+		if sinceFetch > jwksRefreshInterval {
+			o.startFetchLocked()
+		}
+		o.mu.Unlock()
+		return key, nil
+	}
+	if o.fetching == nil && sinceFetch < jwksMinRefetch {
+		o.mu.Unlock()
+		return signingKey{}, fmt.Errorf("unknown signing key %q", kid)
+	}
+	done := o.startFetchLocked()
+	o.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return signingKey{}, ctx.Err()
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if key, ok = o.lookup(kid); !ok {
+		return signingKey{}, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// lookup finds a key by ID; tokens without an ID are accepted when the set has a single key.
+// The caller holds o.mu.
+func (o *OIDCAuth) lookup(kid string) (signingKey, bool) {
+	if kid == "" && len(o.keys) == 1 {
+		for _, k := range o.keys {
+			return k, true
+		}
+	}
+	key, ok := o.keys[kid]
+	return key, ok
+}
+
+// startFetchLocked starts fetching the key set unless a fetch is already running, and returns a
+// channel closed when that fetch completes. The caller holds o.mu.
+func (o *OIDCAuth) startFetchLocked() <-chan struct{} {
+	if o.fetching != nil {
+		return o.fetching
+	}
+	done := make(chan struct{})
+	o.fetching = done
+	jwksURL := o.jwksURL
+
+	go func() {
+		// The fetch is shared by every waiting request, so it is not tied to any of them.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		jwksURL, keys, err := o.fetchKeys(ctx, jwksURL)
+
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		// Failed attempts count too, so an unreachable issuer is not hammered.
+		o.fetchedAt = time.Now()
+		if err != nil {
+			log.Printf("oidc: failed to fetch signing keys: %v", err)
+		} else {
+			o.jwksURL = jwksURL
+			o.keys = keys
+		}
+		o.fetching = nil
+		close(done)
+	}()
+	return done
+}
+
+// fetchKeys discovers the JWKS URL if it is not known yet and loads the key set.
+func (o *OIDCAuth) fetchKeys(ctx context.Context, jwksURL string) (string, map[string]signingKey, error) {
+	if jwksURL == "" {
+		var discovery struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		url := strings.TrimSuffix(o.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+		if err := o.getJSON(ctx, url, &discovery); err != nil {
+			return "", nil, err
+		}
+		if discovery.JWKSURI == "" {
+			return "", nil, errors.New("discovery document has no jwks_uri")
+		}
+		jwksURL = discovery.JWKSURI
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := o.getJSON(ctx, jwksURL, &set); err != nil {
+		return "", nil, err
+	}
+	keys := make(map[string]signingKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Keys of unsupported types are skipped rather than failing the whole set.
+		if key, err := jwk.signingKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	if len(keys) == 0 {
+		return "", nil, errors.New("key set has no usable signing keys")
+	}
+	return jwksURL, keys, nil
+}
+
+func (o *OIDCAuth) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := o.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// signingKey decodes the key and works out which algorithm it verifies: an EC key's is fixed by
+// its curve, an RSA key's is its alg when the key set gives one.
+func (k jsonWebKey) signingKey() (signingKey, error) {
+	key, err := k.publicKey()
+	if err != nil {
+		return signingKey{}, err
+	}
+	alg := k.Alg
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if alg != "" && !strings.HasPrefix(alg, "RS") {
+			return signingKey{}, fmt.Errorf("algorithm %s does not match an RSA key", alg)
+		}
+	case *ecdsa.PublicKey:
+		curveAlg := map[string]string{"P-256": "ES256", "P-384": "ES384", "P-521": "ES512"}[key.Curve.Params().Name]
+		if alg != "" && alg != curveAlg {
+			return signingKey{}, fmt.Errorf("algorithm %s does not match curve %s", alg, key.Curve.Params().Name)
+		}
+		alg = curveAlg
+	}
+	return signingKey{key: key, alg: alg}, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
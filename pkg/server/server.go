@@ -3,20 +3,46 @@ package server
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/GetStream/tikv-ui/pkg/services"
+	"github.com/GetStream/tikv-ui/pkg/types"
 	"github.com/GetStream/tikv-ui/pkg/utils"
 	"github.com/tikv/client-go/v2/rawkv"
+	"github.com/tikv/client-go/v2/txnkv"
 )
 
 // ClusterConnection represents a connection to a TiKV cluster
 type ClusterConnection struct {
-	Name      string
-	PDAddrs   []string
+	Name    string
+	PDAddrs []string
+	// Mode is types.ClusterModeRaw or types.ClusterModeTxn; only the matching client is set.
+	Mode string
+	// ReadOnly clusters can be browsed but every mutating endpoint rejects them.
+	ReadOnly  bool
 	Client    *rawkv.Client
+	TxnClient *txnkv.Client
 	ClusterID uint64
 }
 
+// IsTxn reports whether the cluster is accessed through transactions rather than RawKV.
+func (c *ClusterConnection) IsTxn() bool {
+	return c.Mode == types.ClusterModeTxn
+}
+
+// Close releases the cluster's client.
+func (c *ClusterConnection) Close() error {
+	if c.TxnClient != nil {
+		return c.TxnClient.Close()
+	}
+	if c.Client != nil {
+		return c.Client.Close()
+	}
+	return nil
+}
+
 // Server holds TiKV client connections and provides HTTP handlers
 type Server struct {
 	mu             sync.RWMutex
@@ -24,28 +50,70 @@ type Server struct {
 	activeCluster  string
 	defaultPDAddrs []string
 	Cache          *utils.Cache
+	// Confirmations holds the tokens that guard destructive operations such as delete-range.
+	Confirmations *utils.TokenStore
+	// KeyLayouts decodes and builds structured keys; nil when no layouts are configured.
+	KeyLayouts *utils.KeyLayouts
+	// Audit records every mutation; nil when auditing is disabled.
+	Audit *services.AuditLog
+	// History keeps recent single-key writes so they can be reverted; nil when disabled.
+	History *utils.History
 }
 
-// New creates a new Server instance with an initial connection
-func New(client *rawkv.Client, pdAddrs []string, name string, cache *utils.Cache) *Server {
-	defaultConn := &ClusterConnection{
-		Name:      name,
-		PDAddrs:   pdAddrs,
-		Client:    client,
-		ClusterID: client.ClusterID(),
+// Connect opens a client for the cluster in its configured mode
+func Connect(ctx context.Context, cluster types.Cluster) (*ClusterConnection, error) {
+	conn := &ClusterConnection{
+		Name:     cluster.Name,
+		PDAddrs:  cluster.PDAddrs,
+		Mode:     cluster.Mode,
+		ReadOnly: cluster.ReadOnly,
+	}
+
+	switch cluster.Mode {
+	case "", types.ClusterModeRaw:
+		client, err := rawkv.NewClientWithOpts(ctx, cluster.PDAddrs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to cluster: %w", err)
+		}
+		client.SetAtomicForCAS(true)
+		conn.Mode = types.ClusterModeRaw
+		conn.Client = client
+		conn.ClusterID = client.ClusterID()
+	case types.ClusterModeTxn:
+		client, err := txnkv.NewClient(cluster.PDAddrs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to cluster: %w", err)
+		}
+		conn.TxnClient = client
+		conn.ClusterID = client.GetClusterID()
+	default:
+		return nil, fmt.Errorf("unsupported cluster mode %q (expected raw or txn)", cluster.Mode)
 	}
+	return conn, nil
+}
 
+// New creates a new Server instance with an initial connection
+func New(conn *ClusterConnection, cache *utils.Cache) *Server {
 	return &Server{
 		clusters: map[string]*ClusterConnection{
-			name: defaultConn,
+			conn.Name: conn,
 		},
-		activeCluster:  name,
-		defaultPDAddrs: pdAddrs,
+		activeCluster:  conn.Name,
+		defaultPDAddrs: conn.PDAddrs,
 		Cache:          cache,
+		Confirmations:  utils.NewTokenStore(5 * time.Minute),
 	}
 }
 
-// GetActiveClient returns the currently active TiKV client
+// GetActiveConnection returns the currently active cluster connection
+func (s *Server) GetActiveConnection() *ClusterConnection {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.clusters[s.activeCluster]
+}
+
+// GetActiveClient returns the currently active TiKV RawKV client, or nil for txn-mode clusters
 func (s *Server) GetActiveClient() *rawkv.Client {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -57,30 +125,71 @@ func (s *Server) GetActiveClient() *rawkv.Client {
 }
 
 // AddCluster adds a new cluster connection
-func (s *Server) AddCluster(ctx context.Context, name string, pdAddrs []string) (*ClusterConnection, error) {
+func (s *Server) AddCluster(ctx context.Context, cluster types.Cluster) (*ClusterConnection, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// Check if cluster already exists
-	if _, exists := s.clusters[name]; exists {
-		return nil, fmt.Errorf("cluster '%s' already exists", name)
+	if _, exists := s.clusters[cluster.Name]; exists {
+		return nil, fmt.Errorf("cluster '%s' already exists", cluster.Name)
 	}
 
-	// Create new client
-	client, err := rawkv.NewClientWithOpts(ctx, pdAddrs)
+	conn, err := Connect(ctx, cluster)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to cluster: %w", err)
+		return nil, err
 	}
+	s.inheritReadOnly(conn)
 
-	conn := &ClusterConnection{
-		Name:      name,
-		PDAddrs:   pdAddrs,
-		Client:    client,
-		ClusterID: client.ClusterID(),
+	s.clusters[cluster.Name] = conn
+	return conn, nil
+}
+
+// inheritReadOnly makes conn read-only when it reaches a cluster that is already registered as
+// read-only, so connecting again under another name cannot be used to write to it. Clusters are
+// matched by cluster ID, and by PD address in case the ID is not known. The caller holds s.mu.
+func (s *Server) inheritReadOnly(conn *ClusterConnection) {
+	if conn.ReadOnly {
+		return
 	}
+	for _, other := range s.clusters {
+		if !other.ReadOnly {
+			continue
+		}
+		if conn.ClusterID != 0 && conn.ClusterID == other.ClusterID || sharesPDAddr(conn.PDAddrs, other.PDAddrs) {
+			conn.ReadOnly = true
+			return
+		}
+	}
+}
 
-	s.clusters[name] = conn
-	return conn, nil
+// sharesPDAddr reports whether the two address lists have an address in common, ignoring case
+// and an http(s) scheme.
+func sharesPDAddr(a, b []string) bool {
+	normalize := func(addr string) string {
+		addr = strings.ToLower(strings.TrimSpace(addr))
+		addr = strings.TrimPrefix(addr, "http://")
+		addr = strings.TrimPrefix(addr, "https://")
+		return strings.TrimSuffix(addr, "/")
+	}
+	seen := make(map[string]bool, len(a))
+	for _, addr := range a {
+		seen[normalize(addr)] = true
+	}
+	for _, addr := range b {
+		if seen[normalize(addr)] {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCluster returns the connection registered under name
+func (s *Server) GetCluster(name string) (*ClusterConnection, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	conn, ok := s.clusters[name]
+	return conn, ok
 }
 
 // SwitchCluster switches the active cluster
@@ -155,8 +264,7 @@ func (s *Server) Close() {
 	defer s.mu.Unlock()
 
 	for _, conn := range s.clusters {
-		if conn.Client != nil {
-			conn.Client.Close()
-		}
+		conn.Close()
 	}
+	s.Audit.Close()
 }
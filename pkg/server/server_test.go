@@ -0,0 +1,28 @@
+package server
+
+import "testing"
+
+func TestInheritReadOnly(t *testing.T) {
+	s := New(&ClusterConnection{Name: "prod", PDAddrs: []string{"pd-1:2379", "pd-2:2379"}, ReadOnly: true, ClusterID: 7}, nil)
+	s.clusters["staging"] = &ClusterConnection{Name: "staging", PDAddrs: []string{"pd-s:2379"}, ClusterID: 9}
+
+	tests := []struct {
+		name string
+		conn ClusterConnection
+		want bool
+	}{
+		{"same address", ClusterConnection{PDAddrs: []string{"http://PD-2:2379"}}, true},
+		{"same cluster ID", ClusterConnection{PDAddrs: []string{"10.0.0.1:2379"}, ClusterID: 7}, true},
+		{"writable cluster", ClusterConnection{PDAddrs: []string{"pd-s:2379"}, ClusterID: 9}, false},
+		{"other cluster", ClusterConnection{PDAddrs: []string{"pd-x:2379"}, ClusterID: 11}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := tt.conn
+			s.inheritReadOnly(&conn)
+			if conn.ReadOnly != tt.want {
+				t.Errorf("ReadOnly = %v, want %v", conn.ReadOnly, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/GetStream/tikv-ui/pkg/types"
+)
+
+// AuditLog appends audit records to a JSONL file. When the file would grow past maxBytes it is
+// rotated to path.1, path.1 to path.2 and so on, keeping at most maxFiles rotated files. A nil
+// *AuditLog is disabled: Record does nothing and Query returns no records.
+type AuditLog struct {
+	path     string
+	maxBytes int64
+	maxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// AuditQuery filters audit records; zero fields match everything.
+type AuditQuery struct {
+	Cluster   string
+	Operation string
+	Identity  string
+	Key       []byte
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+}
+
+// NewAuditLog opens (or creates) the audit log at path.
+func NewAuditLog(path string, maxBytes int64, maxFiles int) (*AuditLog, error) {
+	if maxBytes <= 0 {
+		return nil, errors.New("audit log size limit must be positive")
+	}
+	if maxFiles < 0 {
+		return nil, errors.New("number of rotated audit logs must not be negative")
+	}
+	a := &AuditLog{path: path, maxBytes: maxBytes, maxFiles: maxFiles}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Enabled reports whether records are being kept, so callers can skip collecting them.
+func (a *AuditLog) Enabled() bool {
+	return a != nil
+}
+
+func (a *AuditLog) open() error {
+	f, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	a.file = f
+	a.size = info.Size()
+	return nil
+}
+
+// Record appends rec to the log.
+func (a *AuditLog) Record(rec types.AuditRecord) error {
+	if a == nil {
+		return nil
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.size > 0 && a.size+int64(len(line)) > a.maxBytes {
+		if err := a.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := a.file.Write(line)
+	a.size += int64(n)
+	return err
+}
+
+// rotate shifts the rotated files up by one and starts a new file. The caller holds a.mu.
+func (a *AuditLog) rotate() error {
+	if err := a.file.Close(); err != nil {
+		return err
+	}
+	if a.maxFiles == 0 {
+		if err := os.Remove(a.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return a.open()
+	}
+
+	if err := os.Remove(a.rotatedPath(a.maxFiles)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for i := a.maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(a.rotatedPath(i), a.rotatedPath(i+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(a.path, a.rotatedPath(1)); err != nil {
+		return err
+	}
+	return a.open()
+}
+
+func (a *AuditLog) rotatedPath(i int) string {
+	return fmt.Sprintf("%s.%d", a.path, i)
+}
+
+// Close closes the log file.
+func (a *AuditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.file.Close()
+}
+
+// Query returns the records matching q, newest first, reading the current file and then the
+// rotated ones.
+func (a *AuditLog) Query(q AuditQuery) ([]types.AuditRecord, error) {
+	if a == nil {
+		return nil, nil
+	}
+
+	// Open every file under the lock so a concurrent rotation cannot move them mid-query; the
+	// current file is only read up to its size at this point.
+	a.mu.Lock()
+	files := make([]io.Reader, 0, a.maxFiles+1)
+	var closers []io.Closer
+	for i := 0; i <= a.maxFiles; i++ {
+		path := a.path
+		if i > 0 {
+			path = a.rotatedPath(i)
+		}
+		f, err := os.Open(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			a.mu.Unlock()
+			closeAll(closers)
+			return nil, err
+		}
+		closers = append(closers, f)
+		if i == 0 {
+			files = append(files, io.LimitReader(f, a.size))
+		} else {
+			files = append(files, f)
+		}
+	}
+	a.mu.Unlock()
+	defer closeAll(closers)
+
+	var out []types.AuditRecord
+	for _, f := range files {
+		keep := 0
+		if q.Limit > 0 {
+			keep = q.Limit - len(out)
+		}
+		matches, err := readAuditFile(f, q, keep)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(matches) - 1; i >= 0; i-- {
+			out = append(out, matches[i])
+			if q.Limit > 0 && len(out) == q.Limit {
+				return out, nil
+			}
+		}
+	}
+	return out, nil
+}
+
+// readAuditFile returns the last keep matching records of one file (all of them when keep is 0)
+// in the order they were written. Only those are held in memory, in a ring buffer, so a query
+// stays small however large the file is. Lines that do not parse, such as a record cut short by
+// a crash, are skipped.
+func readAuditFile(r io.Reader, q AuditQuery, keep int) ([]types.AuditRecord, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	var matches []types.AuditRecord
+	next := 0 // where the next match goes once the ring is full
+	for sc.Scan() {
+		var rec types.AuditRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			continue
+		}
+		if !q.matches(rec) {
+			continue
+		}
+		if keep <= 0 || len(matches) < keep {
+			matches = append(matches, rec)
+			continue
+		}
+		matches[next] = rec
+		next = (next + 1) % keep
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	// Rotate the ring so the oldest kept match comes first.
+	return slices.Concat(matches[next:], matches[:next]), nil
+}
+
+func (q AuditQuery) matches(rec types.AuditRecord) bool {
+	switch {
+	case q.Cluster != "" && rec.Cluster != q.Cluster,
+		q.Operation != "" && rec.Operation != q.Operation,
+		q.Identity != "" && rec.Identity != q.Identity,
+		q.Key != nil && !bytes.Equal(rec.Key, q.Key),
+		!q.Since.IsZero() && rec.Time.Before(q.Since),
+		!q.Until.IsZero() && !rec.Time.Before(q.Until):
+		return false
+	}
+	return true
+}
+
+func closeAll(closers []io.Closer) {
+	for _, c := range closers {
+		c.Close()
+	}
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/GetStream/tikv-ui/pkg/types"
+)
+
+var pdHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// pdURL builds a PD HTTP API URL, defaulting to plain HTTP when the address has no scheme.
+func pdURL(pdAddr, path string) string {
+	if strings.HasPrefix(pdAddr, "http") {
+		return pdAddr + path
+	}
+	return "http://" + pdAddr + path
+}
+
+// RegionStats asks PD for the approximate statistics of the regions overlapping [startKey, endKey).
+func RegionStats(ctx context.Context, pdAddr string, startKey, endKey []byte) (types.PDRegionStats, error) {
+	q := url.Values{}
+	q.Set("start_key", string(startKey))
+	q.Set("end_key", string(endKey))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pdURL(pdAddr, "/pd/api/v1/stats/region?"+q.Encode()), nil)
+	if err != nil {
+		return types.PDRegionStats{}, err
+	}
+
+	resp, err := pdHTTPClient.Do(req)
+	if err != nil {
+		return types.PDRegionStats{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return types.PDRegionStats{}, fmt.Errorf("PD returned status %d", resp.StatusCode)
+	}
+
+	var stats types.PDRegionStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return types.PDRegionStats{}, err
+	}
+	return stats, nil
+}
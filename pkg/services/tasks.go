@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GetStream/tikv-ui/pkg/types"
+	"github.com/GetStream/tikv-ui/pkg/utils"
+)
+
+// Task states
+const (
+	TaskRunning   = "running"
+	TaskSucceeded = "succeeded"
+	TaskFailed    = "failed"
+	TaskCanceled  = "canceled"
+)
+
+// maxFinishedTasks bounds how many completed tasks are kept for inspection.
+const maxFinishedTasks = 100
+
+// Task is a cancellable background operation that reports progress while it runs.
+type Task struct {
+	mu     sync.RWMutex
+	info   types.TaskInfo
+	cancel context.CancelFunc
+}
+
+// ID returns the task's ID.
+func (t *Task) ID() string {
+	return t.info.ID
+}
+
+// Update applies fn to the task progress.
+func (t *Task) Update(fn func(p *types.TaskProgress)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fn(&t.info.Progress)
+}
+
+// Info returns a snapshot of the task state.
+func (t *Task) Info() types.TaskInfo {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.info
+}
+
+// TaskManager runs background tasks and keeps their state for polling.
+type TaskManager struct {
+	mu    sync.RWMutex
+	tasks map[string]*Task
+}
+
+func NewTaskManager() *TaskManager {
+	return &TaskManager{
+		tasks: make(map[string]*Task),
+	}
+}
+
+// Start runs fn in the background as a new task of the given kind. The task context is
+// canceled by Cancel; fn should return ctx.Err() when it stops early.
+func (m *TaskManager) Start(kind string, params any, fn func(ctx context.Context, t *Task) error) types.TaskInfo {
+	id, _ := utils.RandHex(8)
+	ctx, cancel := context.WithCancel(context.Background())
+	task := &Task{
+		info: types.TaskInfo{
+			ID:        id,
+			Kind:      kind,
+			Status:    TaskRunning,
+			Params:    params,
+			StartedAt: time.Now(),
+		},
+		cancel: cancel,
+	}
+
+	m.mu.Lock()
+	m.tasks[id] = task
+	m.evictLocked()
+	m.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		err := fn(ctx, task)
+
+		task.mu.Lock()
+		defer task.mu.Unlock()
+		now := time.Now()
+		task.info.FinishedAt = &now
+		switch {
+		case err == nil:
+			task.info.Status = TaskSucceeded
+		case ctx.Err() == context.Canceled:
+			task.info.Status = TaskCanceled
+		default:
+			task.info.Status = TaskFailed
+			task.info.Error = err.Error()
+		}
+	}()
+
+	return task.Info()
+}
+
+// Get returns the state of a task.
+func (m *TaskManager) Get(id string) (types.TaskInfo, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	task, ok := m.tasks[id]
+	if !ok {
+		return types.TaskInfo{}, false
+	}
+	return task.Info(), true
+}
+
+// List returns all known tasks, newest first.
+func (m *TaskManager) List() []types.TaskInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	infos := make([]types.TaskInfo, 0, len(m.tasks))
+	for _, task := range m.tasks {
+		infos = append(infos, task.Info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].StartedAt.After(infos[j].StartedAt)
+	})
+	return infos
+}
+
+// Cancel stops a running task. It reports false if the task does not exist.
+func (m *TaskManager) Cancel(id string) bool {
+	m.mu.RLock()
+	task, ok := m.tasks[id]
+	m.mu.RUnlock()
+
+	if ok {
+		task.cancel()
+	}
+	return ok
+}
+
+// evictLocked drops the oldest finished tasks beyond maxFinishedTasks.
+func (m *TaskManager) evictLocked() {
+	var finished []*Task
+	for _, task := range m.tasks {
+		if task.Info().FinishedAt != nil {
+			finished = append(finished, task)
+		}
+	}
+	if len(finished) <= maxFinishedTasks {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].Info().FinishedAt.Before(*finished[j].Info().FinishedAt)
+	})
+	for _, task := range finished[:len(finished)-maxFinishedTasks] {
+		delete(m.tasks, task.Info().ID)
+	}
+}
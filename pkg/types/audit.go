@@ -0,0 +1,42 @@
+package types
+
+import "time"
+
+// AuditRecord is a single mutation as stored in the audit log. Keys and values are kept as raw
+// bytes (base64 in the log file); a nil PreviousValue means the key did not exist and a nil
+// NewValue that it was deleted.
+type AuditRecord struct {
+	Time      time.Time `json:"time"`
+	Cluster   string    `json:"cluster"`
+	Operation string    `json:"operation"`
+	// Key is the mutated key, or the start of the range for range and bulk operations.
+	Key           []byte `json:"key,omitempty"`
+	PreviousValue []byte `json:"previous_value"`
+	NewValue      []byte `json:"new_value"`
+	// Identity is the authenticated caller, when known.
+	Identity     string `json:"identity,omitempty"`
+	ClientIP     string `json:"client_ip"`
+	ForwardedFor string `json:"forwarded_for,omitempty"`
+	// Details holds operation-specific fields such as the end key of a range or a TTL.
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// AuditEntry is an audit record as returned by the audit endpoint, with keys and values
+// formatted in the requested encodings.
+type AuditEntry struct {
+	Time          time.Time      `json:"time"`
+	Cluster       string         `json:"cluster"`
+	Operation     string         `json:"operation"`
+	Key           string         `json:"key,omitempty"`
+	PreviousValue *string        `json:"previous_value"`
+	NewValue      *string        `json:"new_value"`
+	Identity      string         `json:"identity,omitempty"`
+	ClientIP      string         `json:"client_ip"`
+	ForwardedFor  string         `json:"forwarded_for,omitempty"`
+	Details       map[string]any `json:"details,omitempty"`
+}
+
+// AuditResponse lists audit entries, newest first
+type AuditResponse struct {
+	Entries []AuditEntry `json:"entries"`
+}
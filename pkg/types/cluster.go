@@ -1,6 +1,16 @@
 package types
 
+// Cluster access modes. Raw clusters are read and written through RawKV, txn clusters through
+// transactions, matching whichever API the cluster's own clients use.
+const (
+	ClusterModeRaw = "raw"
+	ClusterModeTxn = "txn"
+)
+
 type Cluster struct {
 	Name    string   `json:"name"`
 	PDAddrs []string `json:"pd_addrs"`
+	Mode    string   `json:"mode,omitempty"`
+	// ReadOnly rejects every write made through the UI.
+	ReadOnly bool `json:"read_only,omitempty"`
 }
@@ -0,0 +1,31 @@
+package types
+
+import "time"
+
+// RevertRequest restores the value a history entry replaced. Cluster defaults to the active one.
+type RevertRequest struct {
+	Cluster string `json:"cluster,omitempty"`
+	ID      uint64 `json:"id"`
+	Encoding
+}
+
+// HistoryItem is a recent write with the value it replaced; a null previous_value means the key
+// did not exist and a null new_value that it was deleted.
+type HistoryItem struct {
+	ID            uint64    `json:"id"`
+	Time          time.Time `json:"time"`
+	Operation     string    `json:"operation"`
+	CF            string    `json:"cf"`
+	Key           string    `json:"key"`
+	PreviousValue *string   `json:"previous_value"`
+	NewValue      *string   `json:"new_value"`
+	// PreviousTTLSeconds is the time to live the previous value had left, restored by a revert.
+	PreviousTTLSeconds uint64 `json:"previous_ttl_seconds,omitempty"`
+	Identity           string `json:"identity,omitempty"`
+}
+
+// HistoryResponse lists a cluster's recent writes, newest first
+type HistoryResponse struct {
+	Cluster string        `json:"cluster"`
+	Items   []HistoryItem `json:"items"`
+}
@@ -0,0 +1,36 @@
+package types
+
+// KeyLayout describes keys made of a fixed prefix followed by typed segments.
+type KeyLayout struct {
+	Name string `json:"name"`
+	// Prefix is written before the segments, in the escaped encoding so binary bytes can be given as \xNN.
+	Prefix   string       `json:"prefix"`
+	Segments []KeySegment `json:"segments"`
+}
+
+// KeySegment is one typed part of a key layout.
+type KeySegment struct {
+	Name string `json:"name"`
+	// Type is string, uint32, uint64, int64, uuid or bytes. Integers are big-endian; int64 has its
+	// sign bit flipped (memcomparable) so negative values sort first. Bytes are given in hex.
+	Type string `json:"type"`
+	// Length fixes the size of string and bytes segments. Without it they run up to the separator,
+	// or to the end of the key for the last segment.
+	Length int `json:"length,omitempty"`
+	// Separator is written after the segment, in the escaped encoding.
+	Separator string `json:"separator,omitempty"`
+}
+
+// StructuredKey is a key given as a layout and its segment values. As input, trailing segments may
+// be left out to build a prefix of the keys below them. uint64 and int64 segments are returned as
+// strings, since JSON numbers cannot hold them exactly.
+type StructuredKey struct {
+	Layout   string         `json:"layout"`
+	Segments map[string]any `json:"segments"`
+}
+
+// UnmarshalJSON keeps numeric segments as json.Number, so 64-bit values are not rounded.
+func (k *StructuredKey) UnmarshalJSON(data []byte) error {
+	type plain StructuredKey
+	return decodeNumbers(data, (*plain)(k))
+}
@@ -52,3 +52,10 @@ type MetricStatus struct {
 	Label   string `json:"label"`
 	Unit    string `json:"unit"`
 }
+
+type PDRegionStats struct {
+	Count       int   `json:"count"`
+	EmptyCount  int   `json:"empty_count"`
+	StorageSize int64 `json:"storage_size"`
+	StorageKeys int64 `json:"storage_keys"`
+}
@@ -1,30 +1,215 @@
 package types
 
+import "encoding/json"
+
+// Encoding selects how keys and values are represented in a request and its response.
+// Supported values are utf8 (default), hex, base64 and escaped.
+type Encoding struct {
+	KeyEncoding   string `json:"key_encoding,omitempty"`
+	ValueEncoding string `json:"value_encoding,omitempty"`
+	// MsgpackExt shows msgpack ext values in full, timestamps included, in the form a msgpack put
+	// stores back unchanged.
+	MsgpackExt bool `json:"msgpack_ext,omitempty"`
+}
+
 // GetRequest represents a request to get a value by key
 type GetRequest struct {
-	Key string `json:"key"`
+	KeyInput
+	// CF selects the column family: default, lock or write.
+	CF string `json:"cf,omitempty"`
+	// SnapshotTS reads a txn-mode cluster as of this timestamp instead of the latest one.
+	SnapshotTS uint64 `json:"snapshot_ts,omitempty"`
+	Encoding
 }
 
 // PutRequest represents a request to put a key-value pair
 type PutRequest struct {
-	Key   string `json:"key"`
+	KeyInput
 	Value string `json:"value"`
+	// ValueFormat is how Value is written: raw (decoded with value_encoding, the default), base64,
+	// hex, json, or msgpack (a JSON document encoded as msgpack).
+	ValueFormat string `json:"value_format,omitempty"`
+	// MsgpackEnvelope prefixes msgpack values with the [0, payload] storage version envelope.
+	MsgpackEnvelope bool `json:"msgpack_envelope,omitempty"`
+	// TTLSeconds expires the key after the given number of seconds; 0 keeps it forever.
+	TTLSeconds uint64 `json:"ttl_seconds,omitempty"`
+	CF         string `json:"cf,omitempty"`
+	Encoding
+}
+
+// PatchRequest edits a stored JSON or msgpack value. Patch is an RFC 6902 JSON Patch (an array of
+// operations) or an RFC 7386 merge patch (an object), applied to the value as get returns it.
+type PatchRequest struct {
+	KeyInput
+	Patch json.RawMessage `json:"patch"`
+	// PatchType is json-patch or merge-patch; it is inferred from Patch when empty.
+	PatchType string `json:"patch_type,omitempty"`
+	CF        string `json:"cf,omitempty"`
+	Encoding
+}
+
+// CASRequest represents a compare-and-swap request. The value is written only if the current
+// value equals previous_value; a missing previous_value means the key must not exist yet.
+type CASRequest struct {
+	Key           string  `json:"key"`
+	PreviousValue *string `json:"previous_value"`
+	Value         string  `json:"value"`
+	Encoding
+}
+
+// TTLRequest represents a request to change the TTL of an existing key
+type TTLRequest struct {
+	Key        string `json:"key"`
+	TTLSeconds uint64 `json:"ttl_seconds"`
+	Encoding
 }
 
 // DeleteRequest represents a request to delete a key
 type DeleteRequest struct {
+	KeyInput
+	CF string `json:"cf,omitempty"`
+	Encoding
+}
+
+// KeyValue is a single key-value pair in a batch request
+type KeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// BatchGetRequest represents a request to get several keys at once
+type BatchGetRequest struct {
+	Keys []string `json:"keys"`
+	Encoding
+}
+
+// BatchPutRequest represents a request to put several key-value pairs at once
+type BatchPutRequest struct {
+	Items []KeyValue `json:"items"`
+	Encoding
+}
+
+// BatchDeleteRequest represents a request to delete several keys at once
+type BatchDeleteRequest struct {
+	Keys []string `json:"keys"`
+	Encoding
+}
+
+// KeyRange selects the keys in [start_key, end_key), or every key starting with prefix.
+// Prefix cannot be combined with explicit bounds.
+type KeyRange struct {
+	StartKey string `json:"start_key"`
+	EndKey   string `json:"end_key"`
+	Prefix   string `json:"prefix,omitempty"`
+	// TiDBPrefix is a prefix given as a (partial) TiDB key, e.g. {"table_id": 45, "kind": "record"}.
+	TiDBPrefix *TiDBKey `json:"tidb_prefix,omitempty"`
+	// StructuredPrefix is a prefix given as a key layout and its leading segments.
+	StructuredPrefix *StructuredKey `json:"structured_prefix,omitempty"`
+}
+
+// KeyInput is the key of a single-key request: an encoded key, or one of the structured forms.
+type KeyInput struct {
 	Key string `json:"key"`
+	// TiDBKey gives the key as a TiDB table or index key.
+	TiDBKey *TiDBKey `json:"tidb_key,omitempty"`
+	// StructuredKey gives the key as a configured key layout and its segment values.
+	StructuredKey *StructuredKey `json:"structured_key,omitempty"`
 }
 
 // ScanRequest represents a request to scan a range of keys
 type ScanRequest struct {
-	StartKey string `json:"start_key"`
-	EndKey   string `json:"end_key"`
-	Limit    int    `json:"limit"`
+	KeyRange
+	Limit int `json:"limit"`
+	// Reverse walks the range [start_key, end_key) from end_key downwards.
+	Reverse bool `json:"reverse,omitempty"`
+	// Cursor resumes a previous scan from its next_cursor.
+	Cursor string `json:"cursor,omitempty"`
+	// KeysOnly skips fetching values entirely.
+	KeysOnly bool `json:"keys_only,omitempty"`
+	// ValuePreviewBytes truncates raw values longer than this and skips parsing them.
+	ValuePreviewBytes int    `json:"value_preview_bytes,omitempty"`
+	CF                string `json:"cf,omitempty"`
+	// Filter keeps only items whose parsed value matches the expression, e.g. `v.status == "failed"`.
+	Filter string `json:"filter,omitempty"`
+	// SnapshotTS reads a txn-mode cluster as of this timestamp; pass the snapshot_ts of the
+	// first page back with the cursor to page through a consistent view.
+	SnapshotTS uint64 `json:"snapshot_ts,omitempty"`
+	// ScanBudget caps how many keys a filtered scan examines before returning.
+	ScanBudget int `json:"scan_budget,omitempty"`
+	Encoding
+}
+
+// DeleteRangeRequest represents a guarded request to delete a range of keys.
+// Without a token it performs a dry run that counts the keys and issues a confirmation token.
+type DeleteRangeRequest struct {
+	KeyRange
+	// Token confirms a previous dry run over the same range and executes the delete.
+	Token string `json:"token,omitempty"`
+	// Force allows an empty start or end bound, i.e. a range reaching the edge of the keyspace.
+	Force bool `json:"force,omitempty"`
+	Encoding
+}
+
+// ExportRequest represents a request to stream a key range as NDJSON
+type ExportRequest struct {
+	KeyRange
+	CF string `json:"cf,omitempty"`
+	// IncludeParsed adds the parsed value to every line.
+	IncludeParsed bool `json:"include_parsed,omitempty"`
+	// PageSize is the number of keys fetched from TiKV per round trip.
+	PageSize int `json:"page_size,omitempty"`
+	Encoding
+}
+
+// CountRequest represents a request to count the keys in a range
+type CountRequest struct {
+	KeyRange
+	// Mode is approximate (PD region statistics, the default) or exact (parallel keys-only scan).
+	Mode string `json:"mode,omitempty"`
+	// Parallelism is the number of regions scanned concurrently in exact mode.
+	Parallelism int `json:"parallelism,omitempty"`
+	Encoding
+}
+
+// DiffRequest represents a request to compare a key range between two registered clusters
+type DiffRequest struct {
+	ClusterA string `json:"cluster_a"`
+	ClusterB string `json:"cluster_b"`
+	KeyRange
+	// Compare is raw (report any byte difference, the default) or parsed (ignore differences
+	// that disappear once both values are decoded).
+	Compare string `json:"compare,omitempty"`
+	// Limit caps the number of reported differences.
+	Limit int `json:"limit"`
+	// ScanBudget caps how many keys are examined per request.
+	ScanBudget int    `json:"scan_budget,omitempty"`
+	Cursor     string `json:"cursor,omitempty"`
+	Encoding
+}
+
+// CopyRequest represents a request to copy a key range between two registered clusters
+type CopyRequest struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	KeyRange
+	// RewriteFrom and RewriteTo replace a key prefix while copying, e.g. "cust:42:" -> "cust:9042:".
+	RewriteFrom string `json:"rewrite_from,omitempty"`
+	RewriteTo   string `json:"rewrite_to,omitempty"`
+	// Policy is overwrite (the default) or skip-existing.
+	Policy string `json:"policy,omitempty"`
+	// KeysPerSecond limits the copy rate; 0 means unlimited.
+	KeysPerSecond int  `json:"keys_per_second,omitempty"`
+	BatchSize     int  `json:"batch_size,omitempty"`
+	DryRun        bool `json:"dry_run,omitempty"`
+	Encoding
 }
 
 // ConnectRequest represents a request to connect to a TiKV cluster
 type ConnectRequest struct {
 	PDAddrs []string `json:"pd_addrs"`
 	Name    string   `json:"name,omitempty"`
+	// Mode is raw (the default) or txn.
+	Mode string `json:"mode,omitempty"`
+	// ReadOnly registers the cluster for browsing only.
+	ReadOnly bool `json:"read_only,omitempty"`
 }
@@ -1,23 +1,191 @@
 package types
 
+import "time"
+
 // GetResponse represents a response from a get operation
 type GetResponse struct {
-	Key      string `json:"key"`
-	Value    any    `json:"value,omitempty"`
-	RawValue string `json:"raw_value,omitempty"`
-	Found    bool   `json:"found"`
+	Key       string `json:"key"`
+	KeyBase64 string `json:"key_base64"`
+	// TiDB is the decoded key when it follows TiDB's table or index layout.
+	TiDB *TiDBKey `json:"tidb,omitempty"`
+	// Structured is the key split into segments when it matches a configured key layout.
+	Structured *StructuredKey `json:"structured,omitempty"`
+	Value      any            `json:"value,omitempty"`
+	RawValue   string         `json:"raw_value,omitempty"`
+	Found      bool           `json:"found"`
+	// ETag fingerprints the raw value; send it as If-Match on put to reject stale writes.
+	ETag string `json:"etag,omitempty"`
+	// TTLSeconds is the remaining time to live, omitted for keys without a TTL.
+	TTLSeconds *uint64 `json:"ttl_seconds,omitempty"`
+	CF         string  `json:"cf,omitempty"`
+	// SnapshotTS is the timestamp a txn-mode cluster was read at.
+	SnapshotTS uint64 `json:"snapshot_ts,omitempty"`
 }
 
 // ScanItem represents a single key-value pair in a scan result
 type ScanItem struct {
-	Key      string `json:"key"`
-	Value    any    `json:"value"`
-	RawValue string `json:"raw_value"`
+	Key        string         `json:"key"`
+	KeyBase64  string         `json:"key_base64"`
+	TiDB       *TiDBKey       `json:"tidb,omitempty"`
+	Structured *StructuredKey `json:"structured,omitempty"`
+	Value      any            `json:"value"`
+	RawValue   string         `json:"raw_value"`
+	ValueSize  *int           `json:"value_size,omitempty"`
+	Truncated  bool           `json:"truncated,omitempty"`
 }
 
 // ScanResponse represents a response from a scan operation
 type ScanResponse struct {
-	Items []ScanItem `json:"items"`
+	Items      []ScanItem `json:"items"`
+	HasMore    bool       `json:"has_more"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+	CF         string     `json:"cf,omitempty"`
+	// Scanned is the number of keys examined, which exceeds len(items) when a filter is applied.
+	Scanned int `json:"scanned"`
+	// SnapshotTS is the timestamp a txn-mode cluster was read at.
+	SnapshotTS uint64 `json:"snapshot_ts,omitempty"`
+}
+
+// BatchGetItem is the per-key result of a batch get
+type BatchGetItem struct {
+	GetResponse
+	Error string `json:"error,omitempty"`
+}
+
+// BatchGetResponse represents a response from a batch get operation
+type BatchGetResponse struct {
+	Items []BatchGetItem `json:"items"`
+}
+
+// BatchResult is the per-key result of a batch mutation
+type BatchResult struct {
+	Key   string `json:"key"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// BatchResponse represents a response from a batch put or delete operation
+type BatchResponse struct {
+	Results   []BatchResult `json:"results"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+}
+
+// DeleteRangeResponse represents a response from a delete-range dry run or execution. A delete that
+// fails part way reports the error along with the keys deleted until then and ResumeKey, the start
+// of the part of the range that may still hold keys.
+type DeleteRangeResponse struct {
+	DryRun    bool       `json:"dry_run"`
+	Count     int        `json:"count,omitempty"`
+	Token     string     `json:"token,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Deleted   bool       `json:"deleted"`
+	Error     string     `json:"error,omitempty"`
+	ResumeKey string     `json:"resume_key,omitempty"`
+}
+
+// CASResponse represents a response from a compare-and-swap operation
+type CASResponse struct {
+	Swapped bool `json:"swapped"`
+	// PreviousValue is the value found before the operation, omitted when the key did not exist.
+	PreviousValue *string `json:"previous_value,omitempty"`
+	ETag          string  `json:"etag,omitempty"`
+}
+
+// ExportItem is a single line of an NDJSON export
+type ExportItem struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Parsed any    `json:"parsed,omitempty"`
+}
+
+// ImportProgress reports the state of a bulk import; the final line has Done set. Truncated means
+// the body could not be read to the end, so records after the last one read were not imported.
+type ImportProgress struct {
+	Read      int      `json:"read"`
+	Written   int      `json:"written"`
+	Skipped   int      `json:"skipped"`
+	Failed    int      `json:"failed"`
+	Truncated bool     `json:"truncated,omitempty"`
+	Done      bool     `json:"done"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// CountResponse represents the key count and size of a range
+type CountResponse struct {
+	Mode       string `json:"mode"`
+	Keys       int64  `json:"keys"`
+	KeyBytes   int64  `json:"key_bytes,omitempty"`
+	ValueBytes int64  `json:"value_bytes,omitempty"`
+	// ApproximateBytes is the size on disk reported by PD, only set in approximate mode.
+	ApproximateBytes int64 `json:"approximate_bytes,omitempty"`
+	// Approximate is set in exact mode when the range changed while it was being counted, which
+	// makes value_bytes an estimate.
+	Approximate bool  `json:"approximate,omitempty"`
+	Regions     int   `json:"regions"`
+	ElapsedMs   int64 `json:"elapsed_ms"`
+}
+
+// Kinds of differences reported by a diff
+const (
+	DiffOnlyA        = "only_a"
+	DiffOnlyB        = "only_b"
+	DiffValueDiffers = "value_differs"
+)
+
+// DiffEntry is a single key that differs between two clusters
+type DiffEntry struct {
+	Key       string  `json:"key"`
+	KeyBase64 string  `json:"key_base64"`
+	Kind      string  `json:"kind"`
+	ValueA    *string `json:"value_a,omitempty"`
+	ValueB    *string `json:"value_b,omitempty"`
+	// ParsedEqual tells, for value_differs entries, whether the decoded values are equal.
+	ParsedEqual *bool `json:"parsed_equal,omitempty"`
+}
+
+// DiffSummary counts what a diff request examined
+type DiffSummary struct {
+	Scanned         int `json:"scanned"`
+	OnlyA           int `json:"only_a"`
+	OnlyB           int `json:"only_b"`
+	Identical       int `json:"identical"`
+	RawDifferent    int `json:"raw_different"`
+	ParsedDifferent int `json:"parsed_different"`
+}
+
+// DiffResponse represents a response from a cross-cluster diff
+type DiffResponse struct {
+	Entries    []DiffEntry `json:"entries"`
+	Summary    DiffSummary `json:"summary"`
+	HasMore    bool        `json:"has_more"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// TaskProgress counts the work done by a background task
+type TaskProgress struct {
+	Scanned int64  `json:"scanned"`
+	Written int64  `json:"written"`
+	Skipped int64  `json:"skipped"`
+	Bytes   int64  `json:"bytes"`
+	LastKey string `json:"last_key,omitempty"`
+}
+
+// TaskInfo represents the state of a background task
+type TaskInfo struct {
+	ID         string       `json:"id"`
+	Kind       string       `json:"kind"`
+	Status     string       `json:"status"`
+	Params     any          `json:"params,omitempty"`
+	Progress   TaskProgress `json:"progress"`
+	Error      string       `json:"error,omitempty"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+}
+
+// TasksResponse represents a list of background tasks
+type TasksResponse struct {
+	Tasks []TaskInfo `json:"tasks"`
 }
 
 // ClusterInfo represents information about a connected cluster
@@ -25,6 +193,8 @@ type ClusterInfo struct {
 	Name      string   `json:"name"`
 	ClusterID uint64   `json:"cluster_id"`
 	PDAddrs   []string `json:"pd_addrs"`
+	Mode      string   `json:"mode"`
+	ReadOnly  bool     `json:"read_only"`
 	Active    bool     `json:"active"`
 }
 
@@ -0,0 +1,57 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Kinds of keys written by TiDB's tablecodec.
+const (
+	TiDBKeyTable  = "table"
+	TiDBKeyRecord = "record"
+	TiDBKeyIndex  = "index"
+)
+
+// TiDBKey is the decoded form of a TiDB table or index key. As input it builds a key (or, when
+// fields are left out, a key prefix): a table ID alone selects the whole table, kind record without
+// a handle selects its rows and an index ID with fewer values selects part of the index.
+type TiDBKey struct {
+	// Kind is table, record or index; it is inferred from the other fields when empty.
+	Kind    string `json:"kind,omitempty"`
+	TableID int64  `json:"table_id"`
+	// Handle is the row ID of a record key; tables with a clustered primary key use CommonHandle.
+	Handle       *int64      `json:"handle,omitempty"`
+	CommonHandle []TiDBDatum `json:"common_handle,omitempty"`
+	IndexID      *int64      `json:"index_id,omitempty"`
+	// IndexValues are the indexed columns; non-unique indexes also carry the row handle last.
+	IndexValues []TiDBDatum `json:"index_values,omitempty"`
+	// Remainder holds trailing bytes that could not be decoded, in hex.
+	Remainder string `json:"remainder,omitempty"`
+	// Encoded is set when the key was wrapped in the memcomparable encoding TiKV stores keys in, as
+	// seen by RawKV reads of a TiDB cluster; TS is the commit timestamp such keys may carry.
+	// Remainder, Encoded and TS are only filled in when decoding.
+	Encoded bool    `json:"encoded,omitempty"`
+	TS      *uint64 `json:"ts,omitempty"`
+}
+
+// TiDBDatum is a single memcomparable-encoded value. Kind is null, int, uint, float, bytes, decimal
+// or duration; bytes are escaped like the escaped encoding, decimals are strings and durations are
+// nanoseconds. Int, uint and duration values are returned as strings, since JSON numbers cannot
+// hold them exactly. As input, Kind may be left out for null, string and whole number values.
+type TiDBDatum struct {
+	Kind  string `json:"kind,omitempty"`
+	Value any    `json:"value"`
+}
+
+// UnmarshalJSON keeps a numeric value as json.Number, so 64-bit values are not rounded.
+func (d *TiDBDatum) UnmarshalJSON(data []byte) error {
+	type plain TiDBDatum
+	return decodeNumbers(data, (*plain)(d))
+}
+
+// decodeNumbers decodes data into v, keeping numbers in untyped fields as json.Number.
+func decodeNumbers(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/tikv/client-go/v2/rawkv"
+)
+
+// Column families exposed by the TiKV raw API.
+const (
+	CFDefault = "default"
+	CFLock    = "lock"
+	CFWrite   = "write"
+)
+
+// ColumnFamily validates cf and returns its normalized name together with the rawkv options
+// selecting it. An empty cf means the default column family.
+func ColumnFamily(cf string) (string, []rawkv.RawOption, error) {
+	switch cf {
+	case "", CFDefault:
+		return CFDefault, nil, nil
+	case CFLock, CFWrite:
+		return cf, []rawkv.RawOption{rawkv.SetColumnFamily(cf)}, nil
+	default:
+		return "", nil, fmt.Errorf("unknown column family %q (expected default, lock or write)", cf)
+	}
+}
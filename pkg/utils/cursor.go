@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ScanCursor records the last key returned by a paginated scan and the scan direction.
+type ScanCursor struct {
+	Key     []byte `json:"k"`
+	Reverse bool   `json:"r,omitempty"`
+}
+
+// EncodeCursor serializes a cursor into an opaque URL-safe token.
+func EncodeCursor(c ScanCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses a token produced by EncodeCursor.
+func DecodeCursor(s string) (ScanCursor, error) {
+	var c ScanCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errors.New("malformed cursor")
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.Key == nil {
+		return c, errors.New("malformed cursor")
+	}
+	return c, nil
+}
+
+// Within reports whether the cursor key lies in [startKey, endKey), an empty endKey meaning no
+// upper bound. A cursor from a scan of the same range always does.
+func (c ScanCursor) Within(startKey, endKey []byte) bool {
+	return bytes.Compare(c.Key, startKey) >= 0 && (len(endKey) == 0 || bytes.Compare(c.Key, endKey) < 0)
+}
+
+// Resume narrows the scan bounds so the scan continues right after the cursor key.
+func (c ScanCursor) Resume(startKey, endKey []byte) ([]byte, []byte) {
+	if c.Reverse {
+		return startKey, c.Key
+	}
+	return NextKey(c.Key), endKey
+}
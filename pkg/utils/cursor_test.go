@@ -0,0 +1,24 @@
+package utils
+
+import "testing"
+
+func TestScanCursorWithin(t *testing.T) {
+	tests := []struct {
+		key, start, end string
+		want            bool
+	}{
+		{"b", "a", "c", true},
+		{"a", "a", "c", true},
+		{"c", "a", "c", false},
+		{"d", "a", "c", false},
+		{"0", "a", "c", false},
+		{"z", "a", "", true},
+		{"a", "", "", true},
+	}
+	for _, tt := range tests {
+		c := ScanCursor{Key: []byte(tt.key)}
+		if got := c.Within([]byte(tt.start), []byte(tt.end)); got != tt.want {
+			t.Errorf("cursor %q within [%q, %q) = %v, want %v", tt.key, tt.start, tt.end, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Supported encodings for keys and values exchanged over the API.
+const (
+	EncodingUTF8    = "utf8"
+	EncodingHex     = "hex"
+	EncodingBase64  = "base64"
+	EncodingEscaped = "escaped"
+)
+
+// ValidateEncoding checks that enc is one of the supported encodings. An empty value is accepted
+// and means the endpoint default.
+func ValidateEncoding(enc string) error {
+	switch enc {
+	case "", EncodingUTF8, EncodingHex, EncodingBase64, EncodingEscaped:
+		return nil
+	default:
+		return fmt.Errorf("unsupported encoding %q (expected utf8, hex, base64 or escaped)", enc)
+	}
+}
+
+// DecodeBytes converts an API string into raw bytes using the given encoding (utf8 when empty).
+func DecodeBytes(s, enc string) ([]byte, error) {
+	switch enc {
+	case "", EncodingUTF8:
+		return []byte(s), nil
+	case EncodingHex:
+		return hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	case EncodingBase64:
+		if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+			return b, nil
+		}
+		return base64.URLEncoding.DecodeString(s)
+	case EncodingEscaped:
+		return Unescape(s)
+	default:
+		return nil, ValidateEncoding(enc)
+	}
+}
+
+// EncodeBytes converts raw bytes into an API string using the given encoding (utf8 when empty).
+// The utf8 encoding is lossy for invalid UTF-8 input; the other encodings round-trip through DecodeBytes.
+func EncodeBytes(b []byte, enc string) string {
+	switch enc {
+	case EncodingHex:
+		return hex.EncodeToString(b)
+	case EncodingBase64:
+		return base64.StdEncoding.EncodeToString(b)
+	case EncodingEscaped:
+		return Escape(b)
+	default:
+		return string(b)
+	}
+}
+
+// FormatValue renders a raw value with the given encoding, falling back to FormatRawValue when none is set.
+func FormatValue(b []byte, enc string) string {
+	if enc == "" {
+		return FormatRawValue(b)
+	}
+	return EncodeBytes(b, enc)
+}
+
+// Escape renders bytes as printable ASCII, writing backslashes as `\\` and every other
+// non-printable byte as `\xNN`.
+func Escape(b []byte) string {
+	var sb strings.Builder
+	sb.Grow(len(b))
+	for _, c := range b {
+		switch {
+		case c == '\\':
+			sb.WriteString(`\\`)
+		case c >= 0x20 && c <= 0x7E:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, `\x%02x`, c)
+		}
+	}
+	return sb.String()
+}
+
+// Unescape reverses Escape. Besides `\xNN` and `\\` it accepts the common `\n`, `\r`, `\t` and `\0` escapes.
+func Unescape(s string) ([]byte, error) {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			out = append(out, c)
+			continue
+		}
+		if i+1 >= len(s) {
+			return nil, fmt.Errorf("trailing backslash at offset %d", i)
+		}
+		i++
+		switch s[i] {
+		case '\\':
+			out = append(out, '\\')
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case 't':
+			out = append(out, '\t')
+		case '0':
+			out = append(out, 0)
+		case 'x':
+			if i+2 >= len(s) {
+				return nil, fmt.Errorf("short \\x escape at offset %d", i-1)
+			}
+			b, err := hex.DecodeString(s[i+1 : i+3])
+			if err != nil {
+				return nil, fmt.Errorf("invalid \\x escape at offset %d", i-1)
+			}
+			out = append(out, b[0])
+			i += 2
+		default:
+			return nil, fmt.Errorf("unknown escape \\%c at offset %d", s[i], i-1)
+		}
+	}
+	return out, nil
+}
+
+// Formats for values written through the API.
+const (
+	ValueFormatRaw     = "raw"
+	ValueFormatBase64  = "base64"
+	ValueFormatHex     = "hex"
+	ValueFormatJSON    = "json"
+	ValueFormatMsgpack = "msgpack"
+)
+
+// EncodeValue converts the value of a write request into the bytes to store. The raw format (the
+// default) decodes value with enc; base64 and hex are shorthands for those encodings; json stores
+// a JSON document after checking it is valid; msgpack encodes a JSON document with
+// EncodeMsgpackJSON, preceded by the version envelope when versioned is set.
+func EncodeValue(value, format, enc string, versioned bool) ([]byte, error) {
+	if format != "" && format != ValueFormatRaw && enc != "" {
+		return nil, fmt.Errorf("value_encoding cannot be combined with value_format %s", format)
+	}
+	if versioned && format != ValueFormatMsgpack {
+		return nil, errors.New("the version envelope is only supported for msgpack values")
+	}
+
+	switch format {
+	case "", ValueFormatRaw:
+		return DecodeBytes(value, enc)
+	case ValueFormatBase64:
+		return DecodeBytes(value, EncodingBase64)
+	case ValueFormatHex:
+		return DecodeBytes(value, EncodingHex)
+	case ValueFormatJSON:
+		if !json.Valid([]byte(value)) {
+			return nil, errors.New("invalid JSON document")
+		}
+		return []byte(value), nil
+	case ValueFormatMsgpack:
+		return EncodeMsgpackJSON([]byte(value), versioned)
+	}
+	return nil, fmt.Errorf("unsupported value_format %q (expected raw, base64, hex, json or msgpack)", format)
+}
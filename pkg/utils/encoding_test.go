@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeDecodeBytesRoundTrip(t *testing.T) {
+	inputs := [][]byte{
+		[]byte("plain:key"),
+		{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0xff},
+		[]byte("back\\slash\n"),
+		{},
+	}
+
+	for _, enc := range []string{EncodingHex, EncodingBase64, EncodingEscaped} {
+		for _, in := range inputs {
+			encoded := EncodeBytes(in, enc)
+			decoded, err := DecodeBytes(encoded, enc)
+			if err != nil {
+				t.Fatalf("DecodeBytes(%q, %s) error: %v", encoded, enc, err)
+			}
+			if !bytes.Equal(decoded, in) {
+				t.Errorf("%s round trip = %v, want %v", enc, decoded, in)
+			}
+		}
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []byte
+		wantErr bool
+	}{
+		{in: `feed:\x00\x01`, want: []byte{'f', 'e', 'e', 'd', ':', 0, 1}},
+		{in: `a\tb\\c`, want: []byte("a\tb\\c")},
+		{in: `\xZZ`, wantErr: true},
+		{in: `\x0`, wantErr: true},
+		{in: `trailing\`, wantErr: true},
+		{in: `\q`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Unescape(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Unescape(%q) expected error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unescape(%q) error: %v", tt.in, err)
+			continue
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("Unescape(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidateEncoding(t *testing.T) {
+	for _, enc := range []string{"", EncodingUTF8, EncodingHex, EncodingBase64, EncodingEscaped} {
+		if err := ValidateEncoding(enc); err != nil {
+			t.Errorf("ValidateEncoding(%q) unexpected error: %v", enc, err)
+		}
+	}
+	if err := ValidateEncoding("latin1"); err == nil {
+		t.Error("ValidateEncoding(latin1) expected error")
+	}
+}
+
+func TestEncodeValue(t *testing.T) {
+	tests := []struct {
+		value, format, enc string
+		versioned          bool
+		want               []byte
+		wantErr            bool
+	}{
+		{value: "hello", want: []byte("hello")},
+		{value: "68656c6c6f", enc: EncodingHex, want: []byte("hello")},
+		{value: "aGVsbG8=", format: ValueFormatBase64, want: []byte("hello")},
+		{value: "00ff", format: ValueFormatHex, want: []byte{0x00, 0xff}},
+		{value: `{"a": 1}`, format: ValueFormatJSON, want: []byte(`{"a": 1}`)},
+		{value: `{"a": 1}`, format: ValueFormatMsgpack, want: []byte{0x81, 0xa1, 'a', 0x01}},
+		{value: `{"a": 1}`, format: ValueFormatMsgpack, versioned: true, want: []byte{0x00, 0x81, 0xa1, 'a', 0x01}},
+		{value: `{"a":`, format: ValueFormatJSON, wantErr: true},
+		{value: "00ff", format: ValueFormatHex, enc: EncodingBase64, wantErr: true},
+		{value: `{"a": 1}`, format: ValueFormatJSON, versioned: true, wantErr: true},
+		{value: "x", format: "yaml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := EncodeValue(tt.value, tt.format, tt.enc, tt.versioned)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("EncodeValue(%q, %q) expected error", tt.value, tt.format)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("EncodeValue(%q, %q) error: %v", tt.value, tt.format, err)
+			continue
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("EncodeValue(%q, %q) = %x, want %x", tt.value, tt.format, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// ETag returns a stable fingerprint of a raw value, used for optimistic concurrency checks.
+func ETag(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:16])
+}
+
+// MatchETag reports whether an If-Match header matches value. It accepts a comma-separated list
+// of quoted or bare tags, weak tags and "*", which matches any existing value.
+func MatchETag(header string, value []byte) bool {
+	if value == nil {
+		return false
+	}
+	want := ETag(value)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		tag = strings.Trim(strings.TrimPrefix(tag, "W/"), `"`)
+		if tag == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,561 @@
+package utils
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Filter is a compiled scan filter evaluated against the output of ParseValue.
+//
+// Expressions address the decoded value as v, with field and index access (v.status, v.items[0],
+// v["odd key"]), and support comparisons (== != < <= > >=) against string, number, bool and null
+// literals, exists(path), `path contains literal`, and the boolean operators &&, || and ! with
+// parentheses, e.g. `v.status == "failed" && exists(v.error)`. A comparison on a path the value
+// does not have is false, whatever the operator.
+type Filter struct {
+	root filterNode
+}
+
+// ParseFilter compiles a filter expression.
+func ParseFilter(expr string) (*Filter, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.peek().text, p.peek().pos)
+	}
+	return &Filter{root: root}, nil
+}
+
+// Match reports whether the parsed value satisfies the filter.
+func (f *Filter) Match(v any) bool {
+	return f.root.eval(v)
+}
+
+type filterNode interface {
+	eval(v any) bool
+}
+
+type andNode struct{ left, right filterNode }
+
+func (n andNode) eval(v any) bool { return n.left.eval(v) && n.right.eval(v) }
+
+type orNode struct{ left, right filterNode }
+
+func (n orNode) eval(v any) bool { return n.left.eval(v) || n.right.eval(v) }
+
+type notNode struct{ inner filterNode }
+
+func (n notNode) eval(v any) bool { return !n.inner.eval(v) }
+
+type existsNode struct{ path filterPath }
+
+func (n existsNode) eval(v any) bool {
+	_, ok := n.path.resolve(v)
+	return ok
+}
+
+type compareNode struct {
+	path    filterPath
+	op      string
+	literal any
+}
+
+func (n compareNode) eval(v any) bool {
+	got, ok := n.path.resolve(v)
+	if !ok {
+		return false
+	}
+
+	switch n.op {
+	case "==":
+		return filterEqual(got, n.literal)
+	case "!=":
+		return !filterEqual(got, n.literal)
+	case "contains":
+		return filterContains(got, n.literal)
+	}
+
+	c, ok := filterCompare(got, n.literal)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// filterPath is a sequence of map keys (string) and array indexes (int) below the root value.
+type filterPath []any
+
+func (p filterPath) resolve(v any) (any, bool) {
+	cur := v
+	for _, step := range p {
+		switch s := step.(type) {
+		case string:
+			m, ok := cur.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if cur, ok = m[s]; !ok {
+				return nil, false
+			}
+		case int:
+			arr, ok := cur.([]any)
+			if !ok || s < 0 || s >= len(arr) {
+				return nil, false
+			}
+			cur = arr[s]
+		}
+	}
+	return cur, true
+}
+
+func filterEqual(a, b any) bool {
+	if an, ok := toFilterNumber(a); ok {
+		bn, ok := toFilterNumber(b)
+		if !ok {
+			return false
+		}
+		c, ok := compareNumbers(an, bn)
+		return ok && c == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func filterCompare(a, b any) (int, bool) {
+	if an, ok := toFilterNumber(a); ok {
+		bn, ok := toFilterNumber(b)
+		if !ok {
+			return 0, false
+		}
+		return compareNumbers(an, bn)
+	}
+	as, ok := a.(string)
+	if !ok {
+		return 0, false
+	}
+	bs, ok := b.(string)
+	if !ok {
+		return 0, false
+	}
+	return strings.Compare(as, bs), true
+}
+
+func filterContains(haystack, needle any) bool {
+	switch h := haystack.(type) {
+	case string:
+		s, ok := needle.(string)
+		return ok && strings.Contains(h, s)
+	case []any:
+		for _, el := range h {
+			if filterEqual(el, needle) {
+				return true
+			}
+		}
+	case map[string]any:
+		s, ok := needle.(string)
+		if !ok {
+			return false
+		}
+		_, found := h[s]
+		return found
+	}
+	return false
+}
+
+// filterNumber holds a number without losing precision: integers stay int64 or uint64, so 64-bit
+// IDs compare exactly, and only fractional values are floats.
+type filterNumber struct {
+	kind byte // 'i', 'u' or 'f'
+	i    int64
+	u    uint64
+	f    float64
+}
+
+func toFilterNumber(v any) (filterNumber, bool) {
+	switch n := v.(type) {
+	case float64:
+		return filterNumber{kind: 'f', f: n}, true
+	case float32:
+		return filterNumber{kind: 'f', f: float64(n)}, true
+	case int:
+		return filterNumber{kind: 'i', i: int64(n)}, true
+	case int8:
+		return filterNumber{kind: 'i', i: int64(n)}, true
+	case int16:
+		return filterNumber{kind: 'i', i: int64(n)}, true
+	case int32:
+		return filterNumber{kind: 'i', i: int64(n)}, true
+	case int64:
+		return filterNumber{kind: 'i', i: n}, true
+	case uint:
+		return filterNumber{kind: 'u', u: uint64(n)}, true
+	case uint8:
+		return filterNumber{kind: 'u', u: uint64(n)}, true
+	case uint16:
+		return filterNumber{kind: 'u', u: uint64(n)}, true
+	case uint32:
+		return filterNumber{kind: 'u', u: uint64(n)}, true
+	case uint64:
+		return filterNumber{kind: 'u', u: n}, true
+	}
+	return filterNumber{}, false
+}
+
+// compareNumbers orders two numbers exactly. Whole floats are compared as integers; only a
+// fractional float makes the comparison fall back to float64. NaN compares with nothing.
+func compareNumbers(a, b filterNumber) (int, bool) {
+	if a.kind == 'f' && b.kind == 'f' {
+		if math.IsNaN(a.f) || math.IsNaN(b.f) {
+			return 0, false
+		}
+		return cmp.Compare(a.f, b.f), true
+	}
+	if a.kind == 'f' {
+		c, ok := compareNumbers(b, a)
+		return -c, ok
+	}
+	if b.kind == 'f' {
+		switch f := b.f; {
+		case math.IsNaN(f):
+			return 0, false
+		case f != math.Trunc(f):
+			return cmp.Compare(a.float(), f), true
+		case f >= 1<<64:
+			return -1, true
+		case f < -(1 << 63):
+			return 1, true
+		case f >= 1<<63:
+			b = filterNumber{kind: 'u', u: uint64(f)}
+		default:
+			b = filterNumber{kind: 'i', i: int64(f)}
+		}
+	}
+
+	// Both are integers now; a negative int64 is below every uint64.
+	switch {
+	case a.kind == 'i' && b.kind == 'i':
+		return cmp.Compare(a.i, b.i), true
+	case a.kind == 'i' && a.i < 0:
+		return -1, true
+	case b.kind == 'i' && b.i < 0:
+		return 1, true
+	}
+	return cmp.Compare(a.unsigned(), b.unsigned()), true
+}
+
+func (n filterNumber) float() float64 {
+	switch n.kind {
+	case 'i':
+		return float64(n.i)
+	case 'u':
+		return float64(n.u)
+	}
+	return n.f
+}
+
+// unsigned returns a non-negative integer as a uint64.
+func (n filterNumber) unsigned() uint64 {
+	if n.kind == 'i' {
+		return uint64(n.i)
+	}
+	return n.u
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+type filterToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenizeFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	i := 0
+	for i < len(expr) {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(expr) && expr[end] != '"' {
+				if expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			tokens = append(tokens, filterToken{kind: tokString, text: expr[i : end+1], pos: i})
+			i = end + 1
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(expr) && strings.IndexByte("0123456789.eE+-", expr[end]) >= 0 {
+				end++
+			}
+			tokens = append(tokens, filterToken{kind: tokNumber, text: expr[i:end], pos: i})
+			i = end
+		case c == '_' || isIdentStart(expr[i:]):
+			end := i
+			for end < len(expr) {
+				r, size := utf8.DecodeRuneInString(expr[end:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				end += size
+			}
+			tokens = append(tokens, filterToken{kind: tokIdent, text: expr[i:end], pos: i})
+			i = end
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", "."} {
+				if strings.HasPrefix(expr[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				r, _ := utf8.DecodeRuneInString(expr[i:])
+				return nil, fmt.Errorf("unexpected character %q at offset %d", r, i)
+			}
+			tokens = append(tokens, filterToken{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, filterToken{kind: tokEOF, pos: len(expr)}), nil
+}
+
+// isIdentStart reports whether s starts with a letter, decoding it as UTF-8.
+func isIdentStart(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsLetter(r)
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *filterParser) accept(kind tokenKind, text string) bool {
+	if t := p.peek(); t.kind == kind && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) expect(kind tokenKind, text string) error {
+	if !p.accept(kind, text) {
+		t := p.peek()
+		return fmt.Errorf("expected %q at offset %d", text, t.pos)
+	}
+	return nil
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokOp, "||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokOp, "&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.accept(tokOp, "!") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	if p.accept(tokOp, "(") {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(tokOp, ")")
+	}
+	if p.accept(tokIdent, "exists") {
+		if err := p.expect(tokOp, "("); err != nil {
+			return nil, err
+		}
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		return existsNode{path}, p.expect(tokOp, ")")
+	}
+	return p.parsePredicate()
+}
+
+func (p *filterParser) parsePredicate() (filterNode, error) {
+	path, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.next()
+	var op string
+	switch {
+	case t.kind == tokOp && (t.text == "==" || t.text == "!=" || t.text == "<" || t.text == "<=" || t.text == ">" || t.text == ">="):
+		op = t.text
+	case t.kind == tokIdent && t.text == "contains":
+		op = t.text
+	case t.kind == tokIdent && t.text == "exists":
+		return existsNode{path}, nil
+	default:
+		return nil, fmt.Errorf("expected comparison operator at offset %d", t.pos)
+	}
+
+	literal, err := p.parseLiteral()
+	if err != nil {
+		return nil, err
+	}
+	return compareNode{path: path, op: op, literal: literal}, nil
+}
+
+func (p *filterParser) parsePath() (filterPath, error) {
+	t := p.next()
+	if t.kind != tokIdent || t.text != "v" {
+		return nil, fmt.Errorf("expected path starting with v at offset %d", t.pos)
+	}
+
+	var path filterPath
+	for {
+		switch {
+		case p.accept(tokOp, "."):
+			field := p.next()
+			if field.kind != tokIdent {
+				return nil, fmt.Errorf("expected field name at offset %d", field.pos)
+			}
+			path = append(path, field.text)
+		case p.accept(tokOp, "["):
+			idx := p.next()
+			switch idx.kind {
+			case tokNumber:
+				n, err := strconv.Atoi(idx.text)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q at offset %d", idx.text, idx.pos)
+				}
+				path = append(path, n)
+			case tokString:
+				var s string
+				if err := json.Unmarshal([]byte(idx.text), &s); err != nil {
+					return nil, fmt.Errorf("invalid string at offset %d", idx.pos)
+				}
+				path = append(path, s)
+			default:
+				return nil, fmt.Errorf("expected index or quoted field at offset %d", idx.pos)
+			}
+			if err := p.expect(tokOp, "]"); err != nil {
+				return nil, err
+			}
+		default:
+			return path, nil
+		}
+	}
+}
+
+func (p *filterParser) parseLiteral() (any, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		var s string
+		if err := json.Unmarshal([]byte(t.text), &s); err != nil {
+			return nil, fmt.Errorf("invalid string at offset %d", t.pos)
+		}
+		return s, nil
+	case tokNumber:
+		// Integers keep their exact value; only fractions and exponents become floats.
+		if !strings.ContainsAny(t.text, ".eE") {
+			if n, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+				return n, nil
+			}
+			if n, err := strconv.ParseUint(t.text, 10, 64); err == nil {
+				return n, nil
+			}
+		}
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at offset %d", t.text, t.pos)
+		}
+		return f, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+	}
+	return nil, fmt.Errorf("expected literal at offset %d", t.pos)
+}
@@ -0,0 +1,82 @@
+package utils
+
+import "testing"
+
+func TestFilterMatch(t *testing.T) {
+	value := map[string]any{
+		"status":  "failed",
+		"retries": int8(3),
+		"score":   1.5,
+		"tags":    []any{"urgent", "billing"},
+		"error":   map[string]any{"code": uint16(500)},
+		"odd key": true,
+		"items":   []any{map[string]any{"id": int64(7)}},
+		"big":     uint64(9007199254740992),
+		"neg":     int64(-1),
+		"größe":   2.5,
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{expr: `v.status == "failed"`, want: true},
+		{expr: `v.status != "failed"`, want: false},
+		{expr: `v.retries >= 3`, want: true},
+		{expr: `v.retries < 3`, want: false},
+		{expr: `v.score > 1`, want: true},
+		{expr: `v.error.code == 500`, want: true},
+		{expr: `v.items[0].id == 7`, want: true},
+		{expr: `v.items[1].id == 7`, want: false},
+		{expr: `v["odd key"] == true`, want: true},
+		{expr: `exists(v.error)`, want: true},
+		{expr: `v.missing exists`, want: false},
+		{expr: `!exists(v.missing)`, want: true},
+		{expr: `v.tags contains "billing"`, want: true},
+		{expr: `v.status contains "fail"`, want: true},
+		{expr: `v.error contains "code"`, want: true},
+		{expr: `v.status == "ok" || (v.retries > 2 && v.tags contains "urgent")`, want: true},
+		{expr: `v.status == "ok" || v.retries > 5`, want: false},
+		{expr: `v.missing == null`, want: false},
+		{expr: `v.missing != null`, want: false},
+		{expr: `v.missing != 1`, want: false},
+		{expr: `!(v.missing == 1)`, want: true},
+		{expr: `v.big == 9007199254740993`, want: false},
+		{expr: `v.big < 9007199254740993`, want: true},
+		{expr: `v.big == 9007199254740992`, want: true},
+		{expr: `v.big == 9007199254740992.0`, want: true},
+		{expr: `v.big > 18446744073709551615`, want: false},
+		{expr: `v.neg < 18446744073709551615`, want: true},
+		{expr: `v.neg == -1`, want: true},
+		{expr: `v.retries < 3.5`, want: true},
+		{expr: `v.größe > 2`, want: true},
+	}
+
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.expr)
+		if err != nil {
+			t.Errorf("ParseFilter(%q) error: %v", tt.expr, err)
+			continue
+		}
+		if got := f.Match(value); got != tt.want {
+			t.Errorf("Filter(%q).Match() = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`status == "failed"`,
+		`v.status = "failed"`,
+		`v.status == "unterminated`,
+		`v.status == "a" &&`,
+		`(v.status == "a"`,
+		`v.items[x] == 1`,
+		`v.status == "a" extra`,
+	} {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("ParseFilter(%q) expected error", expr)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// HistoryEntry is a single-key write together with the value it replaced. A nil PreviousValue
+// means the key did not exist before and a nil NewValue that the write deleted it.
+type HistoryEntry struct {
+	ID            uint64
+	Time          time.Time
+	Cluster       string
+	Operation     string
+	CF            string
+	Key           []byte
+	PreviousValue []byte
+	NewValue      []byte
+	// PreviousTTL is the time to live, in seconds, the replaced value had left when it was
+	// replaced; 0 when it had none.
+	PreviousTTL uint64
+	Identity    string
+}
+
+// History keeps the most recent writes of each cluster in memory, up to limit per cluster. A nil
+// *History keeps nothing.
+type History struct {
+	mu      sync.Mutex
+	limit   int
+	lastID  uint64
+	entries map[string][]HistoryEntry
+}
+
+func NewHistory(limit int) *History {
+	return &History{
+		limit:   limit,
+		entries: make(map[string][]HistoryEntry),
+	}
+}
+
+// Enabled reports whether writes are being kept, so callers can skip reading previous values.
+func (h *History) Enabled() bool {
+	return h != nil
+}
+
+// Add stores e, dropping the cluster's oldest entry when it is full, and returns it with its ID.
+func (h *History) Add(e HistoryEntry) HistoryEntry {
+	if h == nil {
+		return e
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	e.ID = h.lastID
+	entries := append(h.entries[e.Cluster], e)
+	if len(entries) > h.limit {
+		entries = append(entries[:0:0], entries[len(entries)-h.limit:]...)
+	}
+	h.entries[e.Cluster] = entries
+	return e
+}
+
+// List returns the cluster's entries newest first, only those for key when it is not nil.
+func (h *History) List(cluster string, key []byte) []HistoryEntry {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entries := h.entries[cluster]
+	out := make([]HistoryEntry, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		if key == nil || bytes.Equal(entries[i].Key, key) {
+			out = append(out, entries[i])
+		}
+	}
+	return out
+}
+
+// Get returns the cluster's entry with the given ID, if it is still kept.
+func (h *History) Get(cluster string, id uint64) (HistoryEntry, bool) {
+	if h == nil {
+		return HistoryEntry{}, false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, e := range h.entries[cluster] {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return HistoryEntry{}, false
+}
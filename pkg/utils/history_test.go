@@ -0,0 +1,34 @@
+package utils
+
+import "testing"
+
+func TestHistoryBounded(t *testing.T) {
+	h := NewHistory(2)
+	for _, k := range []string{"a", "b", "c"} {
+		h.Add(HistoryEntry{Cluster: "prod", Key: []byte(k)})
+	}
+	h.Add(HistoryEntry{Cluster: "staging", Key: []byte("a")})
+
+	got := h.List("prod", nil)
+	if len(got) != 2 || string(got[0].Key) != "c" || string(got[1].Key) != "b" {
+		t.Fatalf("List(prod) = %+v, want c then b", got)
+	}
+	if _, ok := h.Get("prod", got[1].ID-1); ok {
+		t.Error("the oldest entry should have been dropped")
+	}
+	if e, ok := h.Get("prod", got[0].ID); !ok || string(e.Key) != "c" {
+		t.Errorf("Get(%d) = %+v, %v", got[0].ID, e, ok)
+	}
+	if _, ok := h.Get("staging", got[0].ID); ok {
+		t.Error("entries must not be found through another cluster")
+	}
+	if got := h.List("prod", []byte("b")); len(got) != 1 {
+		t.Errorf("List(prod, b) returned %d entries, want 1", len(got))
+	}
+
+	var disabled *History
+	disabled.Add(HistoryEntry{Cluster: "prod"})
+	if disabled.Enabled() || disabled.List("prod", nil) != nil {
+		t.Error("a nil History should keep nothing")
+	}
+}
@@ -0,0 +1,301 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/GetStream/tikv-ui/pkg/types"
+)
+
+// Key segment types.
+const (
+	SegmentString = "string"
+	SegmentUint32 = "uint32"
+	SegmentUint64 = "uint64"
+	SegmentInt64  = "int64"
+	SegmentUUID   = "uuid"
+	SegmentBytes  = "bytes"
+)
+
+// KeyLayouts builds and decodes keys following the configured layouts. A nil *KeyLayouts has no
+// layouts: Decode matches nothing and Encode fails.
+type KeyLayouts struct {
+	layouts []*keyLayout
+	byName  map[string]*keyLayout
+}
+
+type keyLayout struct {
+	types.KeyLayout
+	prefix     []byte
+	separators [][]byte
+}
+
+// LoadKeyLayouts reads a JSON array of layouts from path.
+func LoadKeyLayouts(path string) (*KeyLayouts, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var layouts []types.KeyLayout
+	if err := json.Unmarshal(data, &layouts); err != nil {
+		return nil, fmt.Errorf("invalid key layouts file: %w", err)
+	}
+	return NewKeyLayouts(layouts)
+}
+
+// NewKeyLayouts validates the layouts and prepares them for use.
+func NewKeyLayouts(layouts []types.KeyLayout) (*KeyLayouts, error) {
+	l := &KeyLayouts{byName: make(map[string]*keyLayout, len(layouts))}
+	for _, layout := range layouts {
+		compiled, err := compileKeyLayout(layout)
+		if err != nil {
+			return nil, fmt.Errorf("layout %q: %w", layout.Name, err)
+		}
+		if _, exists := l.byName[layout.Name]; exists {
+			return nil, fmt.Errorf("layout %q is defined twice", layout.Name)
+		}
+		l.byName[layout.Name] = compiled
+		l.layouts = append(l.layouts, compiled)
+	}
+	// Longer prefixes are more specific, so they are tried first when decoding.
+	sort.SliceStable(l.layouts, func(i, j int) bool {
+		return len(l.layouts[i].prefix) > len(l.layouts[j].prefix)
+	})
+	return l, nil
+}
+
+func compileKeyLayout(layout types.KeyLayout) (*keyLayout, error) {
+	if layout.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if len(layout.Segments) == 0 {
+		return nil, errors.New("at least one segment is required")
+	}
+	prefix, err := Unescape(layout.Prefix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prefix: %w", err)
+	}
+
+	compiled := &keyLayout{KeyLayout: layout, prefix: prefix}
+	names := make(map[string]bool, len(layout.Segments))
+	for i, seg := range layout.Segments {
+		if seg.Name == "" {
+			return nil, fmt.Errorf("segment %d has no name", i)
+		}
+		if names[seg.Name] {
+			return nil, fmt.Errorf("segment %q is defined twice", seg.Name)
+		}
+		names[seg.Name] = true
+
+		sep, err := Unescape(seg.Separator)
+		if err != nil {
+			return nil, fmt.Errorf("segment %q: invalid separator: %w", seg.Name, err)
+		}
+		compiled.separators = append(compiled.separators, sep)
+
+		switch seg.Type {
+		case SegmentString, SegmentBytes:
+			if seg.Length < 0 {
+				return nil, fmt.Errorf("segment %q: length must not be negative", seg.Name)
+			}
+			if seg.Length == 0 && len(sep) == 0 && i != len(layout.Segments)-1 {
+				return nil, fmt.Errorf("segment %q: only the last segment may have neither a length nor a separator", seg.Name)
+			}
+		case SegmentUint32, SegmentUint64, SegmentInt64, SegmentUUID:
+			if seg.Length != 0 {
+				return nil, fmt.Errorf("segment %q: %s segments have a fixed length", seg.Name, seg.Type)
+			}
+		default:
+			return nil, fmt.Errorf("segment %q: unsupported type %q (expected string, uint32, uint64, int64, uuid or bytes)", seg.Name, seg.Type)
+		}
+	}
+	return compiled, nil
+}
+
+// Layouts returns the configured layouts sorted by name.
+func (l *KeyLayouts) Layouts() []types.KeyLayout {
+	if l == nil {
+		return []types.KeyLayout{}
+	}
+	out := make([]types.KeyLayout, 0, len(l.byName))
+	for _, layout := range l.layouts {
+		out = append(out, layout.KeyLayout)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Encode builds the key described by k. Segments may only be left out at the end, in which case
+// the result is the prefix shared by every key with the given leading segments.
+func (l *KeyLayouts) Encode(k *types.StructuredKey) ([]byte, error) {
+	if l == nil {
+		return nil, errors.New("no key layouts are configured")
+	}
+	layout, ok := l.byName[k.Layout]
+	if !ok {
+		return nil, fmt.Errorf("unknown key layout %q", k.Layout)
+	}
+
+	key := bytes.Clone(layout.prefix)
+	used := 0
+	for i, seg := range layout.Segments {
+		v, ok := k.Segments[seg.Name]
+		if !ok {
+			break
+		}
+		var err error
+		if key, err = encodeSegment(key, seg, layout.separators[i], v); err != nil {
+			return nil, fmt.Errorf("segment %q: %w", seg.Name, err)
+		}
+		key = append(key, layout.separators[i]...)
+		used++
+	}
+	if used != len(k.Segments) {
+		return nil, errors.New("segments must be known and may only be left out at the end")
+	}
+	return key, nil
+}
+
+// Decode splits key into named segments using the first layout that matches it completely.
+func (l *KeyLayouts) Decode(key []byte) *types.StructuredKey {
+	if l == nil {
+		return nil
+	}
+	for _, layout := range l.layouts {
+		if !bytes.HasPrefix(key, layout.prefix) {
+			continue
+		}
+		if segments, ok := layout.decode(key[len(layout.prefix):]); ok {
+			return &types.StructuredKey{Layout: layout.Name, Segments: segments}
+		}
+	}
+	return nil
+}
+
+func (layout *keyLayout) decode(rest []byte) (map[string]any, bool) {
+	segments := make(map[string]any, len(layout.Segments))
+	for i, seg := range layout.Segments {
+		sep := layout.separators[i]
+
+		size := segmentSize(seg)
+		switch {
+		case size > 0:
+		case len(sep) > 0:
+			if size = bytes.Index(rest, sep); size < 0 {
+				return nil, false
+			}
+		default:
+			size = len(rest)
+		}
+		if len(rest) < size || !bytes.HasPrefix(rest[size:], sep) {
+			return nil, false
+		}
+
+		v, ok := decodeSegment(seg, rest[:size])
+		if !ok {
+			return nil, false
+		}
+		segments[seg.Name] = v
+		rest = rest[size+len(sep):]
+	}
+	return segments, len(rest) == 0
+}
+
+// segmentSize returns the encoded size of fixed-size segments and 0 for variable-length ones.
+func segmentSize(seg types.KeySegment) int {
+	switch seg.Type {
+	case SegmentUint32:
+		return 4
+	case SegmentUint64, SegmentInt64:
+		return 8
+	case SegmentUUID:
+		return 16
+	}
+	return seg.Length
+}
+
+func decodeSegment(seg types.KeySegment, b []byte) (any, bool) {
+	switch seg.Type {
+	case SegmentString:
+		return string(b), utf8.Valid(b)
+	case SegmentBytes:
+		return hex.EncodeToString(b), true
+	case SegmentUint32:
+		return binary.BigEndian.Uint32(b), true
+	case SegmentUint64:
+		// 64-bit values are returned as strings, which JSON clients cannot round.
+		return strconv.FormatUint(binary.BigEndian.Uint64(b), 10), true
+	case SegmentInt64:
+		return strconv.FormatInt(decodeCmpInt(b), 10), true
+	case SegmentUUID:
+		h := hex.EncodeToString(b)
+		return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], true
+	}
+	return nil, false
+}
+
+func encodeSegment(dst []byte, seg types.KeySegment, sep []byte, v any) ([]byte, error) {
+	switch seg.Type {
+	case SegmentString, SegmentBytes:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %v", v)
+		}
+		b := []byte(s)
+		if seg.Type == SegmentBytes {
+			var err error
+			if b, err = hex.DecodeString(s); err != nil {
+				return nil, fmt.Errorf("invalid hex: %w", err)
+			}
+		}
+		if seg.Length > 0 && len(b) != seg.Length {
+			return nil, fmt.Errorf("expected %d bytes, got %d", seg.Length, len(b))
+		}
+		if seg.Length == 0 && len(sep) > 0 && bytes.Contains(b, sep) {
+			return nil, fmt.Errorf("value must not contain the separator %q", seg.Separator)
+		}
+		return append(dst, b...), nil
+	case SegmentUint32:
+		n, err := datumUint(v)
+		if err != nil {
+			return nil, err
+		}
+		if n > math.MaxUint32 {
+			return nil, fmt.Errorf("%d does not fit in a uint32", n)
+		}
+		return binary.BigEndian.AppendUint32(dst, uint32(n)), nil
+	case SegmentUint64:
+		n, err := datumUint(v)
+		if err != nil {
+			return nil, err
+		}
+		return binary.BigEndian.AppendUint64(dst, n), nil
+	case SegmentInt64:
+		n, err := datumInt(v)
+		if err != nil {
+			return nil, err
+		}
+		return encodeCmpInt(dst, n), nil
+	case SegmentUUID:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a UUID string, got %v", v)
+		}
+		b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+		if err != nil || len(b) != 16 {
+			return nil, fmt.Errorf("invalid UUID %q", s)
+		}
+		return append(dst, b...), nil
+	}
+	return nil, fmt.Errorf("unsupported type %q", seg.Type)
+}
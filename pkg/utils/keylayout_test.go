@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/GetStream/tikv-ui/pkg/types"
+)
+
+func testKeyLayouts(t *testing.T) *KeyLayouts {
+	t.Helper()
+	layouts, err := NewKeyLayouts([]types.KeyLayout{
+		{Name: "feed", Prefix: "feed:", Segments: []types.KeySegment{
+			{Name: "user_id", Type: SegmentUint64, Separator: ":"},
+			{Name: "kind", Type: SegmentString, Separator: ":"},
+			{Name: "ts", Type: SegmentInt64},
+		}},
+		{Name: "session", Prefix: `s\x00`, Segments: []types.KeySegment{
+			{Name: "id", Type: SegmentUUID},
+			{Name: "shard", Type: SegmentUint32},
+			{Name: "tag", Type: SegmentBytes},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewKeyLayouts: %v", err)
+	}
+	return layouts
+}
+
+func TestKeyLayoutRoundTrip(t *testing.T) {
+	layouts := testKeyLayouts(t)
+	tests := []struct {
+		input string
+		want  types.StructuredKey
+	}{
+		{
+			`{"layout": "feed", "segments": {"user_id": 42, "kind": "post", "ts": -5}}`,
+			types.StructuredKey{Layout: "feed", Segments: map[string]any{"user_id": "42", "kind": "post", "ts": "-5"}},
+		},
+		{
+			// Past 2^53, where a float64 would round the ID to 9007199254740992.
+			`{"layout": "feed", "segments": {"user_id": 9007199254740993, "kind": "post", "ts": "-9223372036854775808"}}`,
+			types.StructuredKey{Layout: "feed", Segments: map[string]any{"user_id": "9007199254740993", "kind": "post", "ts": "-9223372036854775808"}},
+		},
+		{
+			`{"layout": "session", "segments": {"id": "0f8fad5b-d9cb-469f-a165-70867728950e", "shard": 7, "tag": "00ff"}}`,
+			types.StructuredKey{Layout: "session", Segments: map[string]any{"id": "0f8fad5b-d9cb-469f-a165-70867728950e", "shard": uint32(7), "tag": "00ff"}},
+		},
+	}
+
+	for _, tt := range tests {
+		var input types.StructuredKey
+		if err := json.Unmarshal([]byte(tt.input), &input); err != nil {
+			t.Fatal(err)
+		}
+		key, err := layouts.Encode(&input)
+		if err != nil {
+			t.Fatalf("Encode(%s): %v", tt.input, err)
+		}
+		got := layouts.Decode(key)
+		if got == nil || !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("Decode(%x) = %+v, want %+v", key, got, tt.want)
+		}
+	}
+}
+
+func TestKeyLayoutInexactNumbers(t *testing.T) {
+	layouts := testKeyLayouts(t)
+	for _, v := range []any{float64(1 << 60), 1.5, json.Number("1.5"), json.Number("18446744073709551616")} {
+		if _, err := layouts.Encode(&types.StructuredKey{Layout: "feed", Segments: map[string]any{"user_id": v}}); err == nil {
+			t.Errorf("Encode accepted user_id %v", v)
+		}
+	}
+}
+
+func TestKeyLayoutPrefix(t *testing.T) {
+	layouts := testKeyLayouts(t)
+
+	prefix, err := layouts.Encode(&types.StructuredKey{Layout: "feed", Segments: map[string]any{"user_id": 42.0}})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	want := []byte("feed:\x00\x00\x00\x00\x00\x00\x00\x2a:")
+	if !bytes.Equal(prefix, want) {
+		t.Errorf("prefix = %q, want %q", prefix, want)
+	}
+	if layouts.Decode(prefix) != nil {
+		t.Error("a partial key should not decode")
+	}
+
+	if _, err := layouts.Encode(&types.StructuredKey{Layout: "feed", Segments: map[string]any{"kind": "post"}}); err == nil {
+		t.Error("expected an error when a leading segment is missing")
+	}
+	if _, err := layouts.Encode(&types.StructuredKey{Layout: "feed", Segments: map[string]any{"user_id": 1.0, "kind": "a:b"}}); err == nil {
+		t.Error("expected an error when a value contains its separator")
+	}
+}
+
+func TestKeyLayoutValidation(t *testing.T) {
+	invalid := [][]types.KeyLayout{
+		{{Name: "a", Segments: []types.KeySegment{{Name: "x", Type: "float"}}}},
+		{{Name: "a", Segments: []types.KeySegment{{Name: "x", Type: SegmentString}, {Name: "y", Type: SegmentUint32}}}},
+		{{Name: "a", Segments: []types.KeySegment{{Name: "x", Type: SegmentUint32, Length: 2}}}},
+		{{Name: "a", Segments: []types.KeySegment{{Name: "x", Type: SegmentUint32}}}, {Name: "a", Segments: []types.KeySegment{{Name: "x", Type: SegmentUint32}}}},
+	}
+	for _, layouts := range invalid {
+		if _, err := NewKeyLayouts(layouts); err == nil {
+			t.Errorf("NewKeyLayouts(%+v) succeeded, want an error", layouts)
+		}
+	}
+
+	var none *KeyLayouts
+	if none.Decode([]byte("feed:")) != nil {
+		t.Error("nil layouts should not decode anything")
+	}
+}
@@ -0,0 +1,22 @@
+package utils
+
+// NextKey returns the smallest key that sorts strictly after k.
+func NextKey(k []byte) []byte {
+	next := make([]byte, len(k)+1)
+	copy(next, k)
+	return next
+}
+
+// PrefixEnd returns the exclusive upper bound of all keys starting with prefix. Trailing 0xFF bytes
+// are dropped before incrementing; a prefix made only of 0xFF bytes has no upper bound and yields nil.
+func PrefixEnd(prefix []byte) []byte {
+	end := make([]byte, len(prefix))
+	copy(end, prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xFF {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrefixEnd(t *testing.T) {
+	tests := []struct {
+		prefix []byte
+		want   []byte
+	}{
+		{prefix: []byte("feed:123:"), want: []byte("feed:123;")},
+		{prefix: []byte{0x01, 0xff}, want: []byte{0x02}},
+		{prefix: []byte{0x01, 0xfe, 0xff, 0xff}, want: []byte{0x01, 0xff}},
+		{prefix: []byte{0xff, 0xff}, want: nil},
+		{prefix: nil, want: nil},
+	}
+
+	for _, tt := range tests {
+		got := PrefixEnd(tt.prefix)
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("PrefixEnd(%x) = %x, want %x", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestScanCursorResume(t *testing.T) {
+	key := []byte{0x00, 0xff, 0x10}
+	token := EncodeCursor(ScanCursor{Key: key})
+
+	cursor, err := DecodeCursor(token)
+	if err != nil {
+		t.Fatalf("DecodeCursor error: %v", err)
+	}
+	start, end := cursor.Resume([]byte{0x00}, []byte{0x01})
+	if !bytes.Equal(start, append(key, 0x00)) || !bytes.Equal(end, []byte{0x01}) {
+		t.Errorf("forward Resume = [%x, %x)", start, end)
+	}
+
+	cursor.Reverse = true
+	start, end = cursor.Resume([]byte{0x00}, []byte{0x01})
+	if !bytes.Equal(start, []byte{0x00}) || !bytes.Equal(end, key) {
+		t.Errorf("reverse Resume = [%x, %x)", start, end)
+	}
+
+	if _, err := DecodeCursor("not a cursor"); err == nil {
+		t.Error("DecodeCursor expected error for malformed token")
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"reflect"
+	"sort"
 	"strconv"
 	"sync"
 	"unicode/utf8"
@@ -16,27 +19,37 @@ import (
 	"github.com/vmihailenco/msgpack/v5/msgpcode"
 )
 
-// msgpackExt handles Stream feeds extension type 0 (e.g. the compact "c" timestamp field).
+// msgpackExt holds a msgpack extension value of one of the types 0-31 Stream feeds use for compact
+// binary fields (e.g. the "c" timestamp field), with its type ID so it can be written back as is.
 type msgpackExt struct {
+	Type    int8
 	Payload []byte
 }
 
-func (e *msgpackExt) MarshalMsgpack() ([]byte, error) {
-	return e.Payload, nil
-}
-
-func (e *msgpackExt) UnmarshalMsgpack(b []byte) error {
-	e.Payload = append([]byte(nil), b...)
-	return nil
+func (e *msgpackExt) EncodeMsgpack(enc *msgpack.Encoder) error {
+	if err := enc.EncodeExtHeader(e.Type, len(e.Payload)); err != nil {
+		return err
+	}
+	_, err := enc.Writer().Write(e.Payload)
+	return err
 }
 
 var registerMsgpackExt sync.Once
 
 func ensureMsgpackExt() {
 	registerMsgpackExt.Do(func() {
-		// Feeds TiKV values use msgpack ext types for compact binary fields (e.g. "c", "v").
+		// Feeds TiKV values use msgpack ext types for compact binary fields (e.g. "c", "v"). Only
+		// decoders are registered, one per ID so the ID is kept; msgpackExt encodes itself.
 		for extID := int8(0); extID < 32; extID++ {
-			msgpack.RegisterExt(extID, (*msgpackExt)(nil))
+			id := extID
+			msgpack.RegisterExtDecoder(id, (*msgpackExt)(nil), func(dec *msgpack.Decoder, v reflect.Value, extLen int) error {
+				payload := make([]byte, extLen)
+				if err := dec.ReadFull(payload); err != nil {
+					return err
+				}
+				*v.Interface().(*msgpackExt) = msgpackExt{Type: id, Payload: payload}
+				return nil
+			})
 		}
 	})
 }
@@ -50,7 +63,19 @@ func FormatRawValue(data []byte) string {
 }
 
 // ParseValue attempts to parse a byte slice as msgpack / JSON and return a structured value.
+// Msgpack ext values holding a nanosecond timestamp are shown as that number.
 func ParseValue(data []byte) (parsed any, raw string) {
+	return parseValue(data, false)
+}
+
+// ParseValueWithExt is ParseValue with every msgpack ext value shown in full, timestamps
+// included: {"$ext": id, "$hex": ..., "$len": n}, plus "$ts" for timestamps. That is the form the
+// msgpack value format of put writes back unchanged.
+func ParseValueWithExt(data []byte) (parsed any, raw string) {
+	return parseValue(data, true)
+}
+
+func parseValue(data []byte, fullExt bool) (parsed any, raw string) {
 	raw = FormatRawValue(data)
 
 	if isPlainText(data) {
@@ -62,7 +87,7 @@ func ParseValue(data []byte) (parsed any, raw string) {
 		return raw, raw
 	}
 
-	decoded, err := decodeMsgpack(data)
+	decoded, err := decodeMsgpack(data, fullExt)
 	if err != nil {
 		if err := json.Unmarshal(data, &decoded); err != nil {
 			return raw, raw
@@ -82,10 +107,10 @@ func ParseValue(data []byte) (parsed any, raw string) {
 	return unwrapVersioned(decoded), raw
 }
 
-func decodeMsgpack(data []byte) (any, error) {
+func decodeMsgpack(data []byte, fullExt bool) (any, error) {
 	ensureMsgpackExt()
 
-	values, err := decodeMsgpackValues(data)
+	values, err := decodeMsgpackValues(data, fullExt)
 	if err != nil {
 		return nil, err
 	}
@@ -100,7 +125,7 @@ func decodeMsgpack(data []byte) (any, error) {
 	}
 }
 
-func decodeMsgpackValues(data []byte) ([]any, error) {
+func decodeMsgpackValues(data []byte, fullExt bool) ([]any, error) {
 	dec := msgpack.NewDecoder(bytes.NewReader(data))
 	dec.UsePreallocateValues(true)
 
@@ -114,7 +139,7 @@ func decodeMsgpackValues(data []byte) ([]any, error) {
 		if err != nil {
 			return nil, err
 		}
-		values = append(values, normalizeDecoded(v))
+		values = append(values, normalizeDecoded(v, fullExt))
 	}
 	return values, nil
 }
@@ -122,53 +147,58 @@ func decodeMsgpackValues(data []byte) ([]any, error) {
 // unwrapVersioned strips the feeds storage version prefix: a leading 0 followed by the payload.
 func unwrapVersioned(v any) any {
 	arr, ok := v.([]any)
-	if !ok || len(arr) != 2 {
+	if !ok || len(arr) != 2 || !isStorageVersion(arr[0]) {
 		return v
 	}
-	if version, ok := arr[0].(int64); ok && version == 0 {
-		return arr[1]
-	}
-	if version, ok := arr[0].(int8); ok && version == 0 {
-		return arr[1]
-	}
-	if version, ok := arr[0].(uint8); ok && version == 0 {
-		return arr[1]
-	}
-	if version, ok := arr[0].(int); ok && version == 0 {
-		return arr[1]
+	return arr[1]
+}
+
+// isStorageVersion reports whether a decoded value is the feeds storage version 0.
+func isStorageVersion(v any) bool {
+	switch version := v.(type) {
+	case int64:
+		return version == 0
+	case int8:
+		return version == 0
+	case uint8:
+		return version == 0
+	case int:
+		return version == 0
 	}
-	return v
+	return false
 }
 
-func normalizeDecoded(v any) any {
+// normalizeDecoded turns a decoded msgpack value into JSON-friendly types. fullExt shows
+// timestamp ext values in full rather than as a number.
+func normalizeDecoded(v any, fullExt bool) any {
 	switch x := v.(type) {
 	case map[string]any:
 		out := make(map[string]any, len(x))
 		for k, val := range x {
-			out[k] = normalizeDecoded(val)
+			out[k] = normalizeDecoded(val, fullExt)
 		}
 		return out
 	case map[any]any:
 		out := make(map[string]any, len(x))
 		for k, val := range x {
-			out[normalizeMapKey(k)] = normalizeDecoded(val)
+			out[normalizeMapKey(k)] = normalizeDecoded(val, fullExt)
 		}
 		return out
 	case []any:
 		out := make([]any, len(x))
 		for i, val := range x {
-			out[i] = normalizeDecoded(val)
+			out[i] = normalizeDecoded(val, fullExt)
 		}
 		return out
 	case *msgpackExt:
 		if x == nil {
 			return nil
 		}
-		return extToJSON(0, x.Payload)
+		return extToJSON(x.Type, x.Payload, fullExt)
 	case msgpackExt:
-		return extToJSON(0, x.Payload)
+		return extToJSON(x.Type, x.Payload, fullExt)
 	case []byte:
-		if nested, ok := tryDecodeNestedMsgpack(x); ok {
+		if nested, ok := tryDecodeNestedMsgpack(x, fullExt); ok {
 			return nested
 		}
 		return bytesToJSON(x)
@@ -178,12 +208,12 @@ func normalizeDecoded(v any) any {
 }
 
 // tryDecodeNestedMsgpack decodes bin fields that embed msgpack values (e.g. feeds "p").
-func tryDecodeNestedMsgpack(data []byte) (any, bool) {
+func tryDecodeNestedMsgpack(data []byte, fullExt bool) (any, bool) {
 	if !looksLikeEmbeddedMsgpack(data) {
 		return nil, false
 	}
 
-	values, err := decodeMsgpackValues(data)
+	values, err := decodeMsgpackValues(data, fullExt)
 	if err != nil || len(values) == 0 {
 		return nil, false
 	}
@@ -260,34 +290,38 @@ func normalizeMapKey(k any) string {
 	}
 }
 
-func extToJSON(extID int8, payload []byte) any {
-	if ts, ok := decodeExtTimestamp(payload); ok {
+// extToJSON represents an ext value as {"$ext": id, "$hex": ..., "$len": n}. Payloads that look like
+// timestamps are shown as the timestamp, or with fullExt as the object with the timestamp added
+// as "$ts", so they can be stored back as ext values.
+func extToJSON(extID int8, payload []byte, fullExt bool) any {
+	ts, _, isTimestamp := decodeExtTimestamp(payload)
+	if isTimestamp && !fullExt {
 		return ts
 	}
-	return map[string]any{
+	out := map[string]any{
 		"$ext": extID,
 		"$hex": hex.EncodeToString(payload),
 		"$len": len(payload),
 	}
+	if isTimestamp {
+		out["$ts"] = ts
+	}
+	return out
 }
 
-// decodeExtTimestamp interprets 8-byte feeds ext payloads as nanosecond timestamps when plausible.
-func decodeExtTimestamp(payload []byte) (int64, bool) {
+// decodeExtTimestamp interprets 8-byte feeds ext payloads as nanosecond timestamps when plausible,
+// and returns the byte order they were found in.
+func decodeExtTimestamp(payload []byte) (int64, binary.ByteOrder, bool) {
 	if len(payload) != 8 {
-		return 0, false
+		return 0, nil, false
 	}
-	for _, n := range []uint64{
-		binary.BigEndian.Uint64(payload),
-		binary.LittleEndian.Uint64(payload),
-	} {
+	for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		n := order.Uint64(payload)
 		if n >= 1e17 && n <= 9e18 {
-			ts := int64(n)
-			if ts > 0 {
-				return ts, true
-			}
+			return int64(n), order, true
 		}
 	}
-	return 0, false
+	return 0, nil, false
 }
 
 // isPlainText checks if the data appears to be plain text (UTF-8, mostly printable)
@@ -321,3 +355,150 @@ func isPlainText(data []byte) bool {
 
 	return float64(printable)/float64(len(data)) > 0.8
 }
+
+// EncodeMsgpackJSON encodes a JSON document as msgpack. Integers keep their exact value and use
+// the smallest msgpack type that fits them, map keys are written in sorted order, and the objects
+// ParseValue produces for binary data are turned back into bin ({"$hex": ...}) and ext
+// ({"$ext": id, "$hex": ...} or {"$ext": id, "$ts": ...}) values. With versioned set the payload is preceded by the feeds
+// storage version 0, the envelope unwrapVersioned strips.
+func EncodeMsgpackJSON(doc []byte, versioned bool) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if dec.More() {
+		return nil, errors.New("invalid JSON: unexpected data after the document")
+	}
+
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	if versioned {
+		if err := enc.EncodeInt(0); err != nil {
+			return nil, err
+		}
+	}
+	if err := encodeJSONValue(enc, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeJSONValue(enc *msgpack.Encoder, v any) error {
+	switch x := v.(type) {
+	case nil:
+		return enc.EncodeNil()
+	case bool:
+		return enc.EncodeBool(x)
+	case string:
+		return enc.EncodeString(x)
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return enc.EncodeInt(n)
+		}
+		if n, err := strconv.ParseUint(x.String(), 10, 64); err == nil {
+			return enc.EncodeUint(n)
+		}
+		f, err := x.Float64()
+		if err != nil {
+			return fmt.Errorf("invalid number %s", x)
+		}
+		return enc.EncodeFloat64(f)
+	case []any:
+		if err := enc.EncodeArrayLen(len(x)); err != nil {
+			return err
+		}
+		for _, el := range x {
+			if err := encodeJSONValue(enc, el); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]any:
+		if _, ok := binaryJSON(x); ok {
+			return encodeBinaryJSON(enc, x)
+		}
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if err := enc.EncodeMapLen(len(keys)); err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := enc.EncodeString(k); err != nil {
+				return err
+			}
+			if err := encodeJSONValue(enc, x[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported JSON value %T", v)
+}
+
+// encodeBinaryJSON writes the {"$hex", "$len"} and {"$ext", "$hex", "$len", "$ts"} objects
+// produced by bytesToJSON and extToJSON. When "$ts" is present it is the value written, as 8 bytes
+// in the byte order of "$hex" (big-endian without it), so a timestamp can be edited through "$ts".
+func encodeBinaryJSON(enc *msgpack.Encoder, obj map[string]any) error {
+	for k := range obj {
+		if k != "$hex" && k != "$len" && k != "$ext" && k != "$ts" {
+			return fmt.Errorf("unexpected field %q in binary value", k)
+		}
+	}
+	var payload []byte
+	if h, ok := obj["$hex"]; ok {
+		s, ok := h.(string)
+		if !ok {
+			return errors.New("$hex must be a string")
+		}
+		var err error
+		if payload, err = hex.DecodeString(s); err != nil {
+			return fmt.Errorf("invalid $hex: %w", err)
+		}
+	}
+
+	ext, isExt := obj["$ext"]
+	if ts, ok := obj["$ts"]; ok {
+		if !isExt {
+			return errors.New("$ts requires $ext")
+		}
+		n, ok := ts.(json.Number)
+		if !ok {
+			return errors.New("$ts must be a number")
+		}
+		v, err := strconv.ParseInt(n.String(), 10, 64)
+		if err != nil || v < 0 {
+			return fmt.Errorf("invalid $ts %s", n)
+		}
+		var order binary.ByteOrder = binary.BigEndian
+		if _, found, ok := decodeExtTimestamp(payload); ok {
+			order = found
+		}
+		payload = make([]byte, 8)
+		order.PutUint64(payload, uint64(v))
+	} else if _, ok := obj["$hex"]; !ok {
+		return errors.New("$hex is required")
+	}
+	if l, ok := obj["$len"]; ok {
+		if n, ok := l.(json.Number); !ok || n.String() != strconv.Itoa(len(payload)) {
+			return fmt.Errorf("$len %v does not match the %d bytes of the value", l, len(payload))
+		}
+	}
+
+	if !isExt {
+		return enc.EncodeBytes(payload)
+	}
+	n, ok := ext.(json.Number)
+	if !ok {
+		return errors.New("$ext must be a number")
+	}
+	id, err := strconv.ParseInt(n.String(), 10, 8)
+	if err != nil {
+		return fmt.Errorf("invalid $ext %s", n)
+	}
+	return (&msgpackExt{Type: int8(id), Payload: payload}).EncodeMsgpack(enc)
+}
@@ -0,0 +1,386 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+// PatchValue applies edit to the value as ParseValue shows it, converted to JSON with json.Number
+// numbers, and encodes the result in the original format. JSON values are written back compactly.
+// For msgpack values everything the edit leaves unchanged keeps its original bytes, so ext types,
+// integer widths, map order and the feeds version envelope survive; changed integers keep their
+// width when the new value still fits in it.
+func PatchValue(data []byte, edit func(doc any) (any, error)) ([]byte, error) {
+	if isPlainText(data) {
+		doc, err := decodeJSONNumbers(data)
+		if err != nil {
+			return nil, errors.New("value is neither JSON nor msgpack")
+		}
+		patched, err := edit(doc)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(patched)
+	}
+
+	prefix, root, err := parseMsgpackDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	view, err := json.Marshal(root.view)
+	if err != nil {
+		return nil, fmt.Errorf("value cannot be represented as JSON: %w", err)
+	}
+	// The edit works on its own copy so the original can be compared against the result.
+	original, _ := decodeJSONNumbers(view)
+	doc, _ := decodeJSONNumbers(view)
+	patched, err := edit(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(prefix)
+	if err := root.encode(msgpack.NewEncoder(&buf), original, patched); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type msgpackNodeKind int
+
+const (
+	msgpackScalar msgpackNodeKind = iota
+	msgpackMap
+	msgpackArray
+	// msgpackNested is a bin value holding msgpack; its only child is the embedded value.
+	msgpackNested
+	// msgpackSequence is a run of concatenated values, shown as an array.
+	msgpackSequence
+)
+
+// msgpackNode is a decoded msgpack value that remembers its encoded bytes.
+type msgpackNode struct {
+	kind     msgpackNodeKind
+	raw      []byte
+	keys     []string
+	keyRaw   [][]byte
+	children []*msgpackNode
+	// view is the value as ParseValue shows it.
+	view any
+	// ext is set for ext values, which a timestamp shown as a number is written back as.
+	ext *msgpackExt
+}
+
+// parseMsgpackDocument splits data into the bytes before the value ParseValue shows (the version
+// envelope, if any) and that value.
+func parseMsgpackDocument(data []byte) ([]byte, *msgpackNode, error) {
+	nodes, err := parseMsgpackNodes(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("value is not valid msgpack: %w", err)
+	}
+
+	switch {
+	case len(nodes) == 0:
+		return nil, nil, errors.New("value is empty")
+	case len(nodes) == 2 && isStorageVersion(nodes[0].view):
+		return nodes[0].raw, nodes[1], nil
+	case len(nodes) == 1 && nodes[0].kind == msgpackArray && len(nodes[0].children) == 2 && isStorageVersion(nodes[0].children[0].view):
+		payload := nodes[0].children[1]
+		return nodes[0].raw[:len(nodes[0].raw)-len(payload.raw)], payload, nil
+	}
+	return nil, sequenceNode(data, nodes), nil
+}
+
+func sequenceNode(data []byte, nodes []*msgpackNode) *msgpackNode {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	view := make([]any, len(nodes))
+	for i, n := range nodes {
+		view[i] = n.view
+	}
+	return &msgpackNode{kind: msgpackSequence, raw: data, children: nodes, view: view}
+}
+
+func parseMsgpackNodes(data []byte) ([]*msgpackNode, error) {
+	ensureMsgpackExt()
+
+	r := bytes.NewReader(data)
+	dec := msgpack.NewDecoder(r)
+	var nodes []*msgpackNode
+	for r.Len() > 0 {
+		node, err := parseMsgpackNode(dec, r, data)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+func parseMsgpackNode(dec *msgpack.Decoder, r *bytes.Reader, data []byte) (*msgpackNode, error) {
+	start := len(data) - r.Len()
+	code, err := dec.PeekCode()
+	if err != nil {
+		return nil, err
+	}
+
+	node := &msgpackNode{}
+	switch {
+	case msgpcode.IsFixedMap(code) || code == msgpcode.Map16 || code == msgpcode.Map32:
+		n, err := dec.DecodeMapLen()
+		if err != nil {
+			return nil, err
+		}
+		view := make(map[string]any, n)
+		node.kind = msgpackMap
+		for range n {
+			keyStart := len(data) - r.Len()
+			var k any
+			if err := dec.Decode(&k); err != nil {
+				return nil, err
+			}
+			key := normalizeMapKey(k)
+			child, err := parseMsgpackNode(dec, r, data)
+			if err != nil {
+				return nil, err
+			}
+			node.keys = append(node.keys, key)
+			node.keyRaw = append(node.keyRaw, data[keyStart:len(data)-len(child.raw)-r.Len()])
+			node.children = append(node.children, child)
+			view[key] = child.view
+		}
+		node.view = view
+	case msgpcode.IsFixedArray(code) || code == msgpcode.Array16 || code == msgpcode.Array32:
+		n, err := dec.DecodeArrayLen()
+		if err != nil {
+			return nil, err
+		}
+		view := make([]any, 0, n)
+		node.kind = msgpackArray
+		for range n {
+			child, err := parseMsgpackNode(dec, r, data)
+			if err != nil {
+				return nil, err
+			}
+			node.children = append(node.children, child)
+			view = append(view, child.view)
+		}
+		node.view = view
+	case code == msgpcode.Bin8 || code == msgpcode.Bin16 || code == msgpcode.Bin32:
+		b, err := dec.DecodeBytes()
+		if err != nil {
+			return nil, err
+		}
+		node.view = bytesToJSON(b)
+		// Mirrors tryDecodeNestedMsgpack.
+		if looksLikeEmbeddedMsgpack(b) {
+			if nested, err := parseMsgpackNodes(b); err == nil && len(nested) > 0 {
+				inner := sequenceNode(b, nested)
+				node.kind = msgpackNested
+				node.children = []*msgpackNode{inner}
+				node.view = inner.view
+			}
+		}
+	default:
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		node.ext, _ = v.(*msgpackExt)
+		node.view = normalizeDecoded(v, false)
+	}
+	node.raw = data[start : len(data)-r.Len()]
+	return node, nil
+}
+
+// encode writes patched, the edited form of the node whose JSON view is original.
+func (n *msgpackNode) encode(enc *msgpack.Encoder, original, patched any) error {
+	if reflect.DeepEqual(original, patched) {
+		_, err := enc.Writer().Write(n.raw)
+		return err
+	}
+
+	switch n.kind {
+	case msgpackScalar:
+		if num, ok := patched.(json.Number); ok {
+			if n.ext != nil {
+				if ok, err := encodeExtTimestamp(enc, n.ext, num); ok || err != nil {
+					return err
+				}
+			}
+			if ok, err := encodeSameWidth(enc, n.raw[0], num); ok || err != nil {
+				return err
+			}
+		}
+	case msgpackMap:
+		if m, ok := patched.(map[string]any); ok {
+			return n.encodeMap(enc, original, m)
+		}
+	case msgpackArray:
+		if a, ok := patched.([]any); ok {
+			if err := enc.EncodeArrayLen(len(a)); err != nil {
+				return err
+			}
+			return n.encodeElements(enc, original, a)
+		}
+	case msgpackSequence:
+		if a, ok := patched.([]any); ok && len(a) > 0 {
+			return n.encodeElements(enc, original, a)
+		}
+	case msgpackNested:
+		if _, isBinary := binaryJSON(patched); !isBinary {
+			var buf bytes.Buffer
+			if err := n.children[0].encode(msgpack.NewEncoder(&buf), original, patched); err != nil {
+				return err
+			}
+			return enc.EncodeBytes(buf.Bytes())
+		}
+	}
+	return encodeJSONValue(enc, patched)
+}
+
+// encodeExtTimestamp writes num as a new timestamp of the ext value orig, with the same ext type
+// and byte order. It reports false when orig does not hold a timestamp.
+func encodeExtTimestamp(enc *msgpack.Encoder, orig *msgpackExt, num json.Number) (bool, error) {
+	_, order, ok := decodeExtTimestamp(orig.Payload)
+	if !ok {
+		return false, nil
+	}
+	ts, err := strconv.ParseInt(num.String(), 10, 64)
+	if err != nil || ts < 0 {
+		return true, fmt.Errorf("invalid timestamp %s", num)
+	}
+	payload := make([]byte, 8)
+	order.PutUint64(payload, uint64(ts))
+	return true, (&msgpackExt{Type: orig.Type, Payload: payload}).EncodeMsgpack(enc)
+}
+
+// binaryJSON reports whether v is one of the objects bytesToJSON and extToJSON produce.
+func binaryJSON(v any) (map[string]any, bool) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	_, hasHex := m["$hex"]
+	_, hasTS := m["$ts"]
+	return m, hasHex || hasTS
+}
+
+// encodeMap keeps the original key order and encoding for surviving keys and appends new keys in
+// sorted order.
+func (n *msgpackNode) encodeMap(enc *msgpack.Encoder, original any, patched map[string]any) error {
+	if err := enc.EncodeMapLen(len(patched)); err != nil {
+		return err
+	}
+	originalMap, _ := original.(map[string]any)
+	written := make(map[string]bool, len(patched))
+	for i, key := range n.keys {
+		v, ok := patched[key]
+		if !ok || written[key] {
+			continue
+		}
+		written[key] = true
+		if _, err := enc.Writer().Write(n.keyRaw[i]); err != nil {
+			return err
+		}
+		if err := n.children[i].encode(enc, originalMap[key], v); err != nil {
+			return err
+		}
+	}
+
+	var added []string
+	for key := range patched {
+		if !written[key] {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+	for _, key := range added {
+		if err := enc.EncodeString(key); err != nil {
+			return err
+		}
+		if err := encodeJSONValue(enc, patched[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeElements writes array elements, reusing an unchanged original element even when inserts
+// or removals shifted it to another index.
+func (n *msgpackNode) encodeElements(enc *msgpack.Encoder, original any, patched []any) error {
+	originalArr, _ := original.([]any)
+	used := make([]bool, len(originalArr))
+	for i, v := range patched {
+		match := -1
+		if i < len(originalArr) && !used[i] && reflect.DeepEqual(originalArr[i], v) {
+			match = i
+		}
+		for j := 0; match < 0 && j < len(originalArr); j++ {
+			if !used[j] && reflect.DeepEqual(originalArr[j], v) {
+				match = j
+			}
+		}
+
+		switch {
+		case match >= 0:
+			used[match] = true
+			if _, err := enc.Writer().Write(n.children[match].raw); err != nil {
+				return err
+			}
+		case i < len(originalArr) && !used[i]:
+			// Edited in place: keep what is unchanged inside it.
+			used[i] = true
+			if err := n.children[i].encode(enc, originalArr[i], v); err != nil {
+				return err
+			}
+		default:
+			if err := encodeJSONValue(enc, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// encodeSameWidth writes num with the fixed-width integer type code, if code is one and num fits.
+func encodeSameWidth(enc *msgpack.Encoder, code byte, num json.Number) (bool, error) {
+	if u, err := strconv.ParseUint(num.String(), 10, 64); err == nil {
+		switch {
+		case code == msgpcode.Uint8 && u <= math.MaxUint8:
+			return true, enc.EncodeUint8(uint8(u))
+		case code == msgpcode.Uint16 && u <= math.MaxUint16:
+			return true, enc.EncodeUint16(uint16(u))
+		case code == msgpcode.Uint32 && u <= math.MaxUint32:
+			return true, enc.EncodeUint32(uint32(u))
+		case code == msgpcode.Uint64:
+			return true, enc.EncodeUint64(u)
+		}
+	}
+	i, err := strconv.ParseInt(num.String(), 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	switch {
+	case code == msgpcode.Int8 && i >= math.MinInt8 && i <= math.MaxInt8:
+		return true, enc.EncodeInt8(int8(i))
+	case code == msgpcode.Int16 && i >= math.MinInt16 && i <= math.MaxInt16:
+		return true, enc.EncodeInt16(int16(i))
+	case code == msgpcode.Int32 && i >= math.MinInt32 && i <= math.MaxInt32:
+		return true, enc.EncodeInt32(int32(i))
+	case code == msgpcode.Int64:
+		return true, enc.EncodeInt64(i)
+	}
+	return false, nil
+}
@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"testing"
 
 	"github.com/vmihailenco/msgpack/v5"
@@ -241,3 +243,107 @@ func TestParseValue_VersionedWrapper(t *testing.T) {
 		t.Fatalf("fid = %v", m["fid"])
 	}
 }
+
+func TestEncodeMsgpackJSON(t *testing.T) {
+	doc := `{"fid": "user:test", "op": 1, "big": 18446744073709551615, "neg": -70000, "ratio": 0.5,
+		"tags": ["a", null, true], "p": {"$hex": "00ff", "$len": 2}, "c": {"$ext": 0, "$hex": "0102", "$len": 2}}`
+
+	for _, versioned := range []bool{false, true} {
+		data, err := EncodeMsgpackJSON([]byte(doc), versioned)
+		if err != nil {
+			t.Fatalf("EncodeMsgpackJSON: %v", err)
+		}
+		if versioned && data[0] != 0x00 {
+			t.Fatalf("versioned payload starts with %#x, want 0x00", data[0])
+		}
+
+		parsed, _ := ParseValue(data)
+		got, err := json.Marshal(parsed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `{"big":18446744073709551615,"c":{"$ext":0,"$hex":"0102","$len":2},"fid":"user:test","neg":-70000,"op":1,"p":{"$hex":"00ff","$len":2},"ratio":0.5,"tags":["a",null,true]}`
+		if string(got) != want {
+			t.Errorf("versioned=%v: ParseValue = %s, want %s", versioned, got, want)
+		}
+	}
+
+	// Small integers take a single byte.
+	data, _ := EncodeMsgpackJSON([]byte(`[1]`), false)
+	if want := []byte{0x91, 0x01}; !bytes.Equal(data, want) {
+		t.Errorf("EncodeMsgpackJSON([1]) = %x, want %x", data, want)
+	}
+
+	for _, invalid := range []string{`{"a":`, `{"$hex": "zz"}`, `{"$hex": "00", "$len": 2}`, `{"$hex": "00", "x": 1}`, `1 2`} {
+		if _, err := EncodeMsgpackJSON([]byte(invalid), false); err == nil {
+			t.Errorf("EncodeMsgpackJSON(%s) succeeded, want an error", invalid)
+		}
+	}
+}
+
+func TestMsgpackExtRoundTrip(t *testing.T) {
+	ensureMsgpackExt()
+
+	little := []byte{0x00, 0x40, 0x4d, 0x59, 0x00, 0x00, 0x00, 0x18} // a timestamp stored little-endian
+	original, err := msgpack.Marshal(map[string]any{
+		"c": &msgpackExt{Type: 3, Payload: little},
+		"v": &msgpackExt{Type: 17, Payload: []byte{0xd0, 0x90}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Timestamps read as plain numbers by default.
+	parsed, _ := ParseValue(original)
+	doc, err := json.Marshal(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"c":1729382258408505344,"v":{"$ext":17,"$hex":"d090","$len":2}}`
+	if string(doc) != want {
+		t.Fatalf("ParseValue = %s, want %s", doc, want)
+	}
+
+	parsed, _ = ParseValueWithExt(original)
+	if doc, err = json.Marshal(parsed); err != nil {
+		t.Fatal(err)
+	}
+	want = `{"c":{"$ext":3,"$hex":"00404d5900000018","$len":8,"$ts":1729382258408505344},"v":{"$ext":17,"$hex":"d090","$len":2}}`
+	if string(doc) != want {
+		t.Fatalf("ParseValueWithExt = %s, want %s", doc, want)
+	}
+
+	// The full form is stored back byte for byte.
+	data, err := EncodeMsgpackJSON(doc, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, original) {
+		t.Errorf("round trip = %x, want %x", data, original)
+	}
+
+	// An edited $ts is written in the byte order of $hex.
+	data, err = EncodeMsgpackJSON([]byte(`{"$ext": 3, "$ts": 1729382258408505345, "$hex": "00404d5900000018"}`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0xd7, 0x03, 0x01, 0x40, 0x4d, 0x59, 0x00, 0x00, 0x00, 0x18}; !bytes.Equal(data, want) {
+		t.Errorf("edited $ts = %x, want %x", data, want)
+	}
+
+	if _, err := EncodeMsgpackJSON([]byte(`{"$ts": 1}`), false); err == nil {
+		t.Error("$ts without $ext succeeded, want an error")
+	}
+
+	// Patching the timestamp, shown as a number, keeps it an ext value of the same type and order.
+	data, err = PatchValue(original, func(doc any) (any, error) {
+		doc.(map[string]any)["c"] = json.Number("1729382258408505345")
+		return doc, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0xd7, 0x03, 0x01, 0x40, 0x4d, 0x59, 0x00, 0x00, 0x00, 0x18}; !bytes.Contains(data, want) {
+		t.Errorf("patched value %x does not hold the ext timestamp %x", data, want)
+	}
+}
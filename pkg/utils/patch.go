@@ -0,0 +1,304 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Patch types accepted by ApplyPatch.
+const (
+	PatchTypeJSON  = "json-patch"
+	PatchTypeMerge = "merge-patch"
+)
+
+// ApplyPatch applies an RFC 6902 JSON Patch or an RFC 7386 merge patch to doc, a value decoded
+// from JSON with json.Number numbers. doc may be modified in place. An empty patchType picks JSON
+// Patch for arrays and merge patch for objects.
+func ApplyPatch(doc any, patch []byte, patchType string) (any, error) {
+	if patchType == "" {
+		patchType = PatchTypeMerge
+		if trimmed := bytes.TrimSpace(patch); len(trimmed) > 0 && trimmed[0] == '[' {
+			patchType = PatchTypeJSON
+		}
+	}
+
+	switch patchType {
+	case PatchTypeJSON:
+		var ops []jsonPatchOp
+		if err := json.Unmarshal(patch, &ops); err != nil {
+			return nil, fmt.Errorf("invalid JSON Patch: %w", err)
+		}
+		for i, op := range ops {
+			var err error
+			if doc, err = op.apply(doc); err != nil {
+				return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+			}
+		}
+		return doc, nil
+	case PatchTypeMerge:
+		p, err := decodeJSONNumbers(patch)
+		if err != nil {
+			return nil, fmt.Errorf("invalid merge patch: %w", err)
+		}
+		return mergePatch(doc, p), nil
+	}
+	return nil, fmt.Errorf("unsupported patch type %q (expected json-patch or merge-patch)", patchType)
+}
+
+// decodeJSONNumbers decodes a JSON document, keeping numbers as json.Number.
+func decodeJSONNumbers(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the document")
+	}
+	return v, nil
+}
+
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = make(map[string]any, len(p))
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+	return t
+}
+
+type jsonPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (op jsonPatchOp) value() (any, error) {
+	if op.Value == nil {
+		return nil, errors.New("value is required")
+	}
+	return decodeJSONNumbers(op.Value)
+}
+
+func (op jsonPatchOp) apply(doc any) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	case "remove":
+		return pointerRemove(doc, path)
+	case "replace":
+		v, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return v, nil
+		}
+		if doc, err = pointerRemove(doc, path); err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	case "test":
+		v, err := op.value()
+		if err != nil {
+			return nil, err
+		}
+		got, err := pointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(got, v) {
+			return nil, errors.New("test failed")
+		}
+		return doc, nil
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		v, err := pointerGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if len(from) == 0 && len(path) == 0 {
+				return doc, nil
+			}
+			if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+				return nil, errors.New("cannot move a value into itself")
+			}
+			if doc, err = pointerRemove(doc, from); err != nil {
+				return nil, err
+			}
+		} else if v, err = deepCopyJSON(v); err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	}
+	return nil, fmt.Errorf("unsupported op %q", op.Op)
+}
+
+func deepCopyJSON(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSONNumbers(b)
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped reference tokens.
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(p, "/") {
+		return nil, fmt.Errorf("invalid JSON Pointer %q", p)
+	}
+	tokens := strings.Split(p[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses an array reference token; "-" and n == len are only valid when adding.
+func arrayIndex(token string, n int, adding bool) (int, error) {
+	if adding && token == "-" {
+		return n, nil
+	}
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if idx > n || (idx == n && !adding) {
+		return 0, fmt.Errorf("array index %d out of range", idx)
+	}
+	return idx, nil
+}
+
+func pointerGet(doc any, path []string) (any, error) {
+	cur := doc
+	for _, token := range path {
+		switch c := cur.(type) {
+		case map[string]any:
+			v, ok := c[token]
+			if !ok {
+				return nil, fmt.Errorf("field %q not found", token)
+			}
+			cur = v
+		case []any:
+			idx, err := arrayIndex(token, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			cur = c[idx]
+		default:
+			return nil, fmt.Errorf("cannot descend into %q", token)
+		}
+	}
+	return cur, nil
+}
+
+// pointerUpdate replaces the container holding the last token of path with update(container, token).
+func pointerUpdate(doc any, path []string, update func(container any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return update(doc, path[0])
+	}
+	switch c := doc.(type) {
+	case map[string]any:
+		child, ok := c[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("field %q not found", path[0])
+		}
+		updated, err := pointerUpdate(child, path[1:], update)
+		if err != nil {
+			return nil, err
+		}
+		c[path[0]] = updated
+		return c, nil
+	case []any:
+		idx, err := arrayIndex(path[0], len(c), false)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := pointerUpdate(c[idx], path[1:], update)
+		if err != nil {
+			return nil, err
+		}
+		c[idx] = updated
+		return c, nil
+	}
+	return nil, fmt.Errorf("cannot descend into %q", path[0])
+}
+
+func pointerAdd(doc any, path []string, v any) (any, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+	return pointerUpdate(doc, path, func(container any, token string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			c[token] = v
+			return c, nil
+		case []any:
+			idx, err := arrayIndex(token, len(c), true)
+			if err != nil {
+				return nil, err
+			}
+			c = append(c, nil)
+			copy(c[idx+1:], c[idx:])
+			c[idx] = v
+			return c, nil
+		}
+		return nil, fmt.Errorf("cannot add %q to a scalar", token)
+	})
+}
+
+func pointerRemove(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, errors.New("cannot remove the whole document")
+	}
+	return pointerUpdate(doc, path, func(container any, token string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			if _, ok := c[token]; !ok {
+				return nil, fmt.Errorf("field %q not found", token)
+			}
+			delete(c, token)
+			return c, nil
+		case []any:
+			idx, err := arrayIndex(token, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			return append(c[:idx], c[idx+1:]...), nil
+		}
+		return nil, fmt.Errorf("cannot remove %q from a scalar", token)
+	})
+}
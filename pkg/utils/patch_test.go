@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		doc       string
+		patch     string
+		patchType string
+		want      string
+		wantErr   bool
+	}{
+		{name: "add and remove", doc: `{"a":1,"b":[1,2]}`, patch: `[{"op":"add","path":"/b/-","value":3},{"op":"remove","path":"/a"}]`, want: `{"b":[1,2,3]}`},
+		{name: "insert", doc: `[1,3]`, patch: `[{"op":"add","path":"/1","value":2}]`, want: `[1,2,3]`},
+		{name: "replace", doc: `{"a":{"b":1}}`, patch: `[{"op":"replace","path":"/a/b","value":"x"}]`, want: `{"a":{"b":"x"}}`},
+		{name: "move", doc: `{"a":1,"b":{}}`, patch: `[{"op":"move","from":"/a","path":"/b/c"}]`, want: `{"b":{"c":1}}`},
+		{name: "copy", doc: `{"a":[1]}`, patch: `[{"op":"copy","from":"/a","path":"/b"}]`, want: `{"a":[1],"b":[1]}`},
+		{name: "escaped pointer", doc: `{"a/b":1,"c~d":2}`, patch: `[{"op":"remove","path":"/a~1b"},{"op":"replace","path":"/c~0d","value":3}]`, want: `{"c~d":3}`},
+		{name: "replace root", doc: `{"a":1}`, patch: `[{"op":"replace","path":"","value":[1,2]}]`, want: `[1,2]`},
+		{name: "add root", doc: `{"a":1}`, patch: `[{"op":"add","path":"","value":{"b":2}}]`, want: `{"b":2}`},
+		{name: "move to root", doc: `{"a":{"b":1},"c":2}`, patch: `[{"op":"move","from":"/a","path":""}]`, want: `{"b":1}`},
+		{name: "copy to root", doc: `{"a":[1]}`, patch: `[{"op":"copy","from":"/a","path":""}]`, want: `[1]`},
+		{name: "move root onto itself", doc: `{"a":1}`, patch: `[{"op":"move","from":"","path":""}]`, want: `{"a":1}`},
+		{name: "test passes", doc: `{"a":1}`, patch: `[{"op":"test","path":"/a","value":1}]`, want: `{"a":1}`},
+		{name: "test fails", doc: `{"a":1}`, patch: `[{"op":"test","path":"/a","value":2}]`, wantErr: true},
+		{name: "missing field", doc: `{"a":1}`, patch: `[{"op":"replace","path":"/b","value":2}]`, wantErr: true},
+		{name: "index out of range", doc: `[1]`, patch: `[{"op":"remove","path":"/1"}]`, wantErr: true},
+		{name: "merge patch", doc: `{"a":1,"b":{"c":2,"d":3}}`, patch: `{"a":null,"b":{"c":4}}`, want: `{"b":{"c":4,"d":3}}`},
+		{name: "explicit type", doc: `{"a":1}`, patch: `{"a":2}`, patchType: PatchTypeJSON, wantErr: true},
+		{name: "unknown type", doc: `{}`, patch: `{}`, patchType: "xml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, _ := decodeJSONNumbers([]byte(tt.doc))
+			got, err := ApplyPatch(doc, []byte(tt.patch), tt.patchType)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyPatch: %v", err)
+			}
+			want, _ := decodeJSONNumbers([]byte(tt.want))
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ApplyPatch = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestPatchValueKeepsUntouchedMsgpack(t *testing.T) {
+	ext := []byte{0xd7, 0x05, 1, 2, 3, 4, 5, 6, 7, 8} // fixext8 with ext type 5
+	nested := []byte{0x81, 0xa1, 'x', 0x01}           // {"x": 1}
+
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	_ = enc.EncodeInt(0) // version envelope
+	_ = enc.EncodeMapLen(5)
+	_ = enc.EncodeString("z")
+	_ = enc.EncodeString("first")
+	_ = enc.EncodeString("c")
+	buf.Write(ext)
+	_ = enc.EncodeString("n")
+	_ = enc.EncodeUint32(7)
+	_ = enc.EncodeString("p")
+	_ = enc.EncodeBytes(nested)
+	_ = enc.EncodeString("l")
+	_ = enc.EncodeArrayLen(3)
+	_ = enc.EncodeInt16(1)
+	_ = enc.EncodeInt16(2)
+	_ = enc.EncodeInt16(3)
+	data := buf.Bytes()
+
+	patch := `[
+		{"op":"replace","path":"/n","value":9},
+		{"op":"replace","path":"/p/x","value":2},
+		{"op":"remove","path":"/l/0"},
+		{"op":"add","path":"/a","value":true}
+	]`
+	got, err := PatchValue(data, func(doc any) (any, error) {
+		return ApplyPatch(doc, []byte(patch), "")
+	})
+	if err != nil {
+		t.Fatalf("PatchValue: %v", err)
+	}
+
+	var want bytes.Buffer
+	enc = msgpack.NewEncoder(&want)
+	_ = enc.EncodeInt(0)
+	_ = enc.EncodeMapLen(6)
+	_ = enc.EncodeString("z")
+	_ = enc.EncodeString("first")
+	_ = enc.EncodeString("c")
+	want.Write(ext)
+	_ = enc.EncodeString("n")
+	_ = enc.EncodeUint32(9)
+	_ = enc.EncodeString("p")
+	_ = enc.EncodeBytes([]byte{0x81, 0xa1, 'x', 0x02})
+	_ = enc.EncodeString("l")
+	_ = enc.EncodeArrayLen(2)
+	_ = enc.EncodeInt16(2)
+	_ = enc.EncodeInt16(3)
+	_ = enc.EncodeString("a")
+	_ = enc.EncodeBool(true)
+
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Errorf("PatchValue =\n%x\nwant\n%x", got, want.Bytes())
+	}
+}
+
+func TestPatchValueUnchanged(t *testing.T) {
+	data := []byte{0x82, 0xa1, 'b', 0xd1, 0x00, 0x01, 0xa1, 'a', 0xc0}
+	got, err := PatchValue(data, func(doc any) (any, error) { return doc, nil })
+	if err != nil {
+		t.Fatalf("PatchValue: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("PatchValue = %x, want the original %x", got, data)
+	}
+}
+
+func TestPatchValueJSON(t *testing.T) {
+	got, err := PatchValue([]byte(`{"a": 1, "b": 2}`), func(doc any) (any, error) {
+		return ApplyPatch(doc, []byte(`{"b":null}`), PatchTypeMerge)
+	})
+	if err != nil {
+		t.Fatalf("PatchValue: %v", err)
+	}
+	var v map[string]any
+	if err := json.Unmarshal(got, &v); err != nil || len(v) != 1 || v["a"] != 1.0 {
+		t.Errorf("PatchValue = %s, want {\"a\":1}", got)
+	}
+
+	if _, err := PatchValue([]byte("not json"), func(doc any) (any, error) { return doc, nil }); err == nil {
+		t.Error("expected an error for a plain text value")
+	}
+}
@@ -31,6 +31,8 @@ func GetClusters(s string) []types.Cluster {
 	return clusters
 }
 
+// GetCluster parses a single "pd1,pd2|name|options" entry. The name and options are optional.
+// Options are comma-separated: the mode (raw, the default, or txn) and readonly.
 func GetCluster(s string) types.Cluster {
 	parts := SplitAndTrim(s, "|")
 	if len(parts) < 2 {
@@ -38,12 +40,26 @@ func GetCluster(s string) types.Cluster {
 		return types.Cluster{
 			Name:    fmt.Sprintf("cluster-%s", hex),
 			PDAddrs: SplitAndTrim(parts[0], ","),
+			Mode:    types.ClusterModeRaw,
 		}
 	}
-	return types.Cluster{
+	cluster := types.Cluster{
 		Name:    parts[1],
 		PDAddrs: SplitAndTrim(parts[0], ","),
+		Mode:    types.ClusterModeRaw,
 	}
+	if len(parts) > 2 {
+		for _, opt := range SplitAndTrim(parts[2], ",") {
+			switch opt {
+			case "readonly", "read-only":
+				cluster.ReadOnly = true
+			default:
+				// Anything else is the mode; Connect rejects unknown ones.
+				cluster.Mode = opt
+			}
+		}
+	}
+	return cluster
 }
 
 func RandHex(n int) (string, error) {
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GetStream/tikv-ui/pkg/types"
+)
+
+func TestGetCluster(t *testing.T) {
+	tests := []struct {
+		in   string
+		want types.Cluster
+	}{
+		{in: "pd1:2379,pd2:2379|prod", want: types.Cluster{Name: "prod", PDAddrs: []string{"pd1:2379", "pd2:2379"}, Mode: types.ClusterModeRaw}},
+		{in: "pd:2379|prod|txn", want: types.Cluster{Name: "prod", PDAddrs: []string{"pd:2379"}, Mode: types.ClusterModeTxn}},
+		{in: "pd:2379|prod|readonly", want: types.Cluster{Name: "prod", PDAddrs: []string{"pd:2379"}, Mode: types.ClusterModeRaw, ReadOnly: true}},
+		{in: "pd:2379|prod| txn , read-only ", want: types.Cluster{Name: "prod", PDAddrs: []string{"pd:2379"}, Mode: types.ClusterModeTxn, ReadOnly: true}},
+	}
+	for _, tt := range tests {
+		if got := GetCluster(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetCluster(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,496 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/GetStream/tikv-ui/pkg/types"
+)
+
+// Datum flags of TiDB's codec package.
+const (
+	tidbNilFlag          byte = 0
+	tidbBytesFlag        byte = 1
+	tidbCompactBytesFlag byte = 2
+	tidbIntFlag          byte = 3
+	tidbUintFlag         byte = 4
+	tidbFloatFlag        byte = 5
+	tidbDecimalFlag      byte = 6
+	tidbDurationFlag     byte = 7
+	tidbVarintFlag       byte = 8
+	tidbUvarintFlag      byte = 9
+)
+
+// Datum kinds as reported in types.TiDBDatum.
+const (
+	tidbKindNull     = "null"
+	tidbKindInt      = "int"
+	tidbKindUint     = "uint"
+	tidbKindFloat    = "float"
+	tidbKindBytes    = "bytes"
+	tidbKindDecimal  = "decimal"
+	tidbKindDuration = "duration"
+)
+
+const tidbSignMask = uint64(1) << 63
+
+var (
+	tidbRecordSep = []byte("_r")
+	tidbIndexSep  = []byte("_i")
+)
+
+// dig2bytes is the number of bytes MySQL's binary decimal format uses for 0-8 leftover digits.
+var dig2bytes = [10]int{0, 1, 1, 2, 2, 3, 3, 4, 4, 4}
+
+// DecodeTiDBKey recognises keys in TiDB's tablecodec layout: t{table_id}_r{handle} and
+// t{table_id}_i{index_id}{values}. Keys are accepted as plain user keys (as read through
+// transactions) or wrapped in the memcomparable encoding TiKV stores them in, optionally followed
+// by a timestamp (as seen by RawKV reads of a TiDB cluster).
+func DecodeTiDBKey(key []byte) (*types.TiDBKey, bool) {
+	if k, ok := decodeTiDBKey(key); ok {
+		return k, true
+	}
+	if len(key) == 0 || key[0] != 't' {
+		return nil, false
+	}
+
+	inner, rest, err := decodeCmpBytes(key)
+	if err != nil || (len(rest) != 0 && len(rest) != 8) {
+		return nil, false
+	}
+	k, ok := decodeTiDBKey(inner)
+	if !ok {
+		return nil, false
+	}
+	k.Encoded = true
+	if len(rest) == 8 {
+		// TiKV appends the timestamp inverted so newer versions sort first.
+		ts := ^binary.BigEndian.Uint64(rest)
+		k.TS = &ts
+	}
+	return k, true
+}
+
+func decodeTiDBKey(key []byte) (*types.TiDBKey, bool) {
+	// Table IDs are positive, so their sign-flipped encoding always has the top bit set. Bare
+	// table prefixes are not stored as keys and are not recognised, which avoids matching any
+	// 9-byte key that happens to start with 't'.
+	if len(key) < 11 || key[0] != 't' || key[1]&0x80 == 0 {
+		return nil, false
+	}
+
+	k := &types.TiDBKey{TableID: decodeCmpInt(key[1:9])}
+	rest := key[9:]
+	switch {
+	case bytes.HasPrefix(rest, tidbRecordSep):
+		k.Kind = types.TiDBKeyRecord
+		rest = rest[len(tidbRecordSep):]
+		if len(rest) == 8 {
+			handle := decodeCmpInt(rest)
+			k.Handle = &handle
+			return k, true
+		}
+		k.CommonHandle, rest = decodeDatums(rest)
+	case bytes.HasPrefix(rest, tidbIndexSep) && len(rest) >= len(tidbIndexSep)+8:
+		k.Kind = types.TiDBKeyIndex
+		rest = rest[len(tidbIndexSep):]
+		indexID := decodeCmpInt(rest[:8])
+		k.IndexID = &indexID
+		k.IndexValues, rest = decodeDatums(rest[8:])
+	default:
+		return nil, false
+	}
+
+	if len(rest) > 0 {
+		k.Remainder = hex.EncodeToString(rest)
+	}
+	return k, true
+}
+
+// EncodeTiDBKey builds the plain tablecodec key described by k. Fields that are left out produce
+// a prefix of the keys below them, e.g. a table ID alone yields the prefix of the whole table.
+func EncodeTiDBKey(k *types.TiDBKey) ([]byte, error) {
+	kind := k.Kind
+	if kind == "" {
+		switch {
+		case k.IndexID != nil || len(k.IndexValues) > 0:
+			kind = types.TiDBKeyIndex
+		case k.Handle != nil || len(k.CommonHandle) > 0:
+			kind = types.TiDBKeyRecord
+		default:
+			kind = types.TiDBKeyTable
+		}
+	}
+
+	key := encodeCmpInt([]byte{'t'}, k.TableID)
+	var datums []types.TiDBDatum
+	switch kind {
+	case types.TiDBKeyTable:
+		if k.Handle != nil || len(k.CommonHandle) > 0 || k.IndexID != nil || len(k.IndexValues) > 0 {
+			return nil, errors.New("table keys cannot have a handle or index fields")
+		}
+		return key, nil
+	case types.TiDBKeyRecord:
+		if k.IndexID != nil || len(k.IndexValues) > 0 {
+			return nil, errors.New("record keys cannot have index fields")
+		}
+		if k.Handle != nil && len(k.CommonHandle) > 0 {
+			return nil, errors.New("handle and common_handle are mutually exclusive")
+		}
+		key = append(key, tidbRecordSep...)
+		if k.Handle != nil {
+			key = encodeCmpInt(key, *k.Handle)
+		}
+		datums = k.CommonHandle
+	case types.TiDBKeyIndex:
+		if k.IndexID == nil {
+			return nil, errors.New("index_id is required for index keys")
+		}
+		if k.Handle != nil || len(k.CommonHandle) > 0 {
+			return nil, errors.New("index keys carry the handle as their last index value")
+		}
+		key = encodeCmpInt(append(key, tidbIndexSep...), *k.IndexID)
+		datums = k.IndexValues
+	default:
+		return nil, fmt.Errorf("unsupported TiDB key kind %q (expected table, record or index)", kind)
+	}
+
+	for i, d := range datums {
+		var err error
+		if key, err = encodeDatum(key, d); err != nil {
+			return nil, fmt.Errorf("value %d: %w", i, err)
+		}
+	}
+	return key, nil
+}
+
+// decodeDatums decodes values until the input runs out or stops being decodable, returning the
+// values and whatever could not be decoded.
+func decodeDatums(b []byte) ([]types.TiDBDatum, []byte) {
+	var datums []types.TiDBDatum
+	for len(b) > 0 {
+		d, rest, err := decodeDatum(b)
+		if err != nil {
+			break
+		}
+		datums = append(datums, d)
+		b = rest
+	}
+	return datums, b
+}
+
+func decodeDatum(b []byte) (types.TiDBDatum, []byte, error) {
+	flag, b := b[0], b[1:]
+	switch flag {
+	case tidbNilFlag:
+		return types.TiDBDatum{Kind: tidbKindNull}, b, nil
+	case tidbIntFlag, tidbDurationFlag:
+		if len(b) < 8 {
+			return types.TiDBDatum{}, nil, errors.New("short int value")
+		}
+		kind := tidbKindInt
+		if flag == tidbDurationFlag {
+			kind = tidbKindDuration
+		}
+		return types.TiDBDatum{Kind: kind, Value: strconv.FormatInt(decodeCmpInt(b[:8]), 10)}, b[8:], nil
+	case tidbUintFlag:
+		if len(b) < 8 {
+			return types.TiDBDatum{}, nil, errors.New("short uint value")
+		}
+		return types.TiDBDatum{Kind: tidbKindUint, Value: strconv.FormatUint(binary.BigEndian.Uint64(b[:8]), 10)}, b[8:], nil
+	case tidbFloatFlag:
+		if len(b) < 8 {
+			return types.TiDBDatum{}, nil, errors.New("short float value")
+		}
+		u := binary.BigEndian.Uint64(b[:8])
+		if u&tidbSignMask != 0 {
+			u &^= tidbSignMask
+		} else {
+			u = ^u
+		}
+		return types.TiDBDatum{Kind: tidbKindFloat, Value: math.Float64frombits(u)}, b[8:], nil
+	case tidbBytesFlag:
+		v, rest, err := decodeCmpBytes(b)
+		if err != nil {
+			return types.TiDBDatum{}, nil, err
+		}
+		return types.TiDBDatum{Kind: tidbKindBytes, Value: Escape(v)}, rest, nil
+	case tidbCompactBytesFlag:
+		n, size := binary.Varint(b)
+		if size <= 0 || n < 0 || int64(len(b)-size) < n {
+			return types.TiDBDatum{}, nil, errors.New("invalid compact bytes value")
+		}
+		end := size + int(n)
+		return types.TiDBDatum{Kind: tidbKindBytes, Value: Escape(b[size:end])}, b[end:], nil
+	case tidbVarintFlag:
+		v, size := binary.Varint(b)
+		if size <= 0 {
+			return types.TiDBDatum{}, nil, errors.New("invalid varint value")
+		}
+		return types.TiDBDatum{Kind: tidbKindInt, Value: strconv.FormatInt(v, 10)}, b[size:], nil
+	case tidbUvarintFlag:
+		v, size := binary.Uvarint(b)
+		if size <= 0 {
+			return types.TiDBDatum{}, nil, errors.New("invalid uvarint value")
+		}
+		return types.TiDBDatum{Kind: tidbKindUint, Value: strconv.FormatUint(v, 10)}, b[size:], nil
+	case tidbDecimalFlag:
+		v, rest, err := decodeCmpDecimal(b)
+		if err != nil {
+			return types.TiDBDatum{}, nil, err
+		}
+		return types.TiDBDatum{Kind: tidbKindDecimal, Value: v}, rest, nil
+	}
+	return types.TiDBDatum{}, nil, fmt.Errorf("unknown datum flag %d", flag)
+}
+
+func encodeDatum(dst []byte, d types.TiDBDatum) ([]byte, error) {
+	kind := d.Kind
+	if kind == "" {
+		switch v := d.Value.(type) {
+		case nil:
+			kind = tidbKindNull
+		case string:
+			kind = tidbKindBytes
+		case float64:
+			kind = tidbKindFloat
+			if v == math.Trunc(v) {
+				kind = tidbKindInt
+			}
+		case json.Number:
+			kind = tidbKindFloat
+			if f, err := v.Float64(); err == nil && f == math.Trunc(f) {
+				kind = tidbKindInt
+			}
+		default:
+			return nil, fmt.Errorf("cannot infer the kind of %v", v)
+		}
+	}
+
+	switch kind {
+	case tidbKindNull:
+		return append(dst, tidbNilFlag), nil
+	case tidbKindInt, tidbKindDuration:
+		n, err := datumInt(d.Value)
+		if err != nil {
+			return nil, err
+		}
+		flag := tidbIntFlag
+		if kind == tidbKindDuration {
+			flag = tidbDurationFlag
+		}
+		return encodeCmpInt(append(dst, flag), n), nil
+	case tidbKindUint:
+		n, err := datumUint(d.Value)
+		if err != nil {
+			return nil, err
+		}
+		return binary.BigEndian.AppendUint64(append(dst, tidbUintFlag), n), nil
+	case tidbKindFloat:
+		f, ok := d.Value.(float64)
+		if n, isNumber := d.Value.(json.Number); isNumber {
+			var err error
+			f, err = n.Float64()
+			ok = err == nil
+		}
+		if !ok {
+			return nil, fmt.Errorf("float value must be a number, got %v", d.Value)
+		}
+		u := math.Float64bits(f)
+		if f >= 0 {
+			u |= tidbSignMask
+		} else {
+			u = ^u
+		}
+		return binary.BigEndian.AppendUint64(append(dst, tidbFloatFlag), u), nil
+	case tidbKindBytes:
+		s, ok := d.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("bytes value must be a string, got %v", d.Value)
+		}
+		b, err := Unescape(s)
+		if err != nil {
+			return nil, err
+		}
+		return encodeCmpBytes(append(dst, tidbBytesFlag), b), nil
+	case tidbKindDecimal:
+		// The encoding depends on the column's precision and scale, which keys do not record.
+		return nil, errors.New("decimal values cannot be encoded, use the preceding values as a prefix")
+	}
+	return nil, fmt.Errorf("unsupported datum kind %q", kind)
+}
+
+// datumInt accepts whole JSON numbers, or strings. Numbers must be exact: a float64 past 2^53 may
+// have been rounded already, so such values have to come as json.Number or as strings.
+func datumInt(v any) (int64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(n.String(), 10, 64); err == nil {
+			return i, nil
+		}
+		f, err := n.Float64()
+		if err != nil || !exactInteger(f) {
+			return 0, fmt.Errorf("%s is not an exact int", n)
+		}
+		return int64(f), nil
+	case float64:
+		if !exactInteger(n) {
+			return 0, fmt.Errorf("%v is not an exact int; send larger values as strings", n)
+		}
+		return int64(n), nil
+	case string:
+		return strconv.ParseInt(n, 10, 64)
+	}
+	return 0, fmt.Errorf("int value must be a number or string, got %v", v)
+}
+
+func datumUint(v any) (uint64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		if u, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+			return u, nil
+		}
+		f, err := n.Float64()
+		if err != nil || f < 0 || !exactInteger(f) {
+			return 0, fmt.Errorf("%s is not an exact uint", n)
+		}
+		return uint64(f), nil
+	case float64:
+		if n < 0 || !exactInteger(n) {
+			return 0, fmt.Errorf("%v is not an exact uint; send larger values as strings", n)
+		}
+		return uint64(n), nil
+	case string:
+		return strconv.ParseUint(n, 10, 64)
+	}
+	return 0, fmt.Errorf("uint value must be a number or string, got %v", v)
+}
+
+// exactInteger reports whether f is a whole number that float64 represents without rounding.
+func exactInteger(f float64) bool {
+	return f == math.Trunc(f) && math.Abs(f) <= 1<<53
+}
+
+// decodeCmpInt reverses encodeCmpInt, which flips the sign bit so integers sort bytewise.
+func decodeCmpInt(b []byte) int64 {
+	return int64(binary.BigEndian.Uint64(b) ^ tidbSignMask)
+}
+
+func encodeCmpInt(dst []byte, v int64) []byte {
+	return binary.BigEndian.AppendUint64(dst, uint64(v)^tidbSignMask)
+}
+
+// decodeCmpBytes reverses encodeCmpBytes, returning the decoded bytes and the input that follows them.
+func decodeCmpBytes(b []byte) ([]byte, []byte, error) {
+	var out []byte
+	for {
+		if len(b) < 9 {
+			return nil, nil, errors.New("short memcomparable group")
+		}
+		group, marker := b[:8], b[8]
+		b = b[9:]
+
+		pad := int(0xFF - marker)
+		if pad > 8 {
+			return nil, nil, fmt.Errorf("invalid memcomparable marker %#x", marker)
+		}
+		out = append(out, group[:8-pad]...)
+		if pad == 0 {
+			continue
+		}
+		for _, c := range group[8-pad:] {
+			if c != 0 {
+				return nil, nil, errors.New("invalid memcomparable padding")
+			}
+		}
+		return out, b, nil
+	}
+}
+
+// encodeCmpBytes writes data in groups of 8 bytes, each followed by a marker byte of 0xFF minus
+// the number of zero bytes padding the group; the last group is always padded.
+func encodeCmpBytes(dst, data []byte) []byte {
+	for i := 0; i <= len(data); i += 8 {
+		if len(data)-i >= 8 {
+			dst = append(append(dst, data[i:i+8]...), 0xFF)
+			continue
+		}
+		pad := 8 - (len(data) - i)
+		dst = append(dst, data[i:]...)
+		dst = append(dst, make([]byte, pad)...)
+		dst = append(dst, byte(0xFF-pad))
+	}
+	return dst
+}
+
+// decodeCmpDecimal decodes a precision byte, a scale byte and MySQL's binary decimal format, in which
+// every 9 digits take 4 bytes, the sign bit is flipped and negative values are inverted.
+func decodeCmpDecimal(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, errors.New("short decimal value")
+	}
+	precision, frac := int(b[0]), int(b[1])
+	if precision > 65 || frac > precision {
+		return "", nil, fmt.Errorf("invalid decimal precision %d and scale %d", precision, frac)
+	}
+
+	intDigits := precision - frac
+	wordsInt, leadingDigits := intDigits/9, intDigits%9
+	wordsFrac, trailingDigits := frac/9, frac%9
+	size := dig2bytes[leadingDigits] + wordsInt*4 + wordsFrac*4 + dig2bytes[trailingDigits]
+	if size == 0 || len(b) < 2+size {
+		return "", nil, errors.New("short decimal value")
+	}
+	bin := bytes.Clone(b[2 : 2+size])
+	rest := b[2+size:]
+
+	negative := bin[0]&0x80 == 0
+	bin[0] ^= 0x80
+	if negative {
+		for i := range bin {
+			bin[i] = ^bin[i]
+		}
+	}
+	read := func(n int) uint64 {
+		var v uint64
+		for _, c := range bin[:n] {
+			v = v<<8 | uint64(c)
+		}
+		bin = bin[n:]
+		return v
+	}
+
+	var sb strings.Builder
+	if leadingDigits > 0 {
+		fmt.Fprintf(&sb, "%d", read(dig2bytes[leadingDigits]))
+	}
+	for range wordsInt {
+		fmt.Fprintf(&sb, "%09d", read(4))
+	}
+	s := strings.TrimLeft(sb.String(), "0")
+	if s == "" {
+		s = "0"
+	}
+
+	if frac > 0 {
+		sb.Reset()
+		for range wordsFrac {
+			fmt.Fprintf(&sb, "%09d", read(4))
+		}
+		if trailingDigits > 0 {
+			fmt.Fprintf(&sb, "%0*d", trailingDigits, read(dig2bytes[trailingDigits]))
+		}
+		s += "." + sb.String()
+	}
+	if negative {
+		s = "-" + s
+	}
+	return s, rest, nil
+}
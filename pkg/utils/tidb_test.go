@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/GetStream/tikv-ui/pkg/types"
+)
+
+func int64Ptr(v int64) *int64 { return &v }
+
+func TestTiDBKeyRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		key  types.TiDBKey
+	}{
+		{name: "record", key: types.TiDBKey{Kind: types.TiDBKeyRecord, TableID: 45, Handle: int64Ptr(-7)}},
+		{name: "common handle", key: types.TiDBKey{Kind: types.TiDBKeyRecord, TableID: 45, CommonHandle: []types.TiDBDatum{
+			{Kind: "bytes", Value: `user\x00one`},
+			{Kind: "int", Value: "3"},
+		}}},
+		{name: "index", key: types.TiDBKey{Kind: types.TiDBKeyIndex, TableID: 45, IndexID: int64Ptr(2), IndexValues: []types.TiDBDatum{
+			{Kind: "bytes", Value: "exactly8"},
+			{Kind: "uint", Value: "9223372036854775808"},
+			{Kind: "float", Value: -2.5},
+			{Kind: "null"},
+			{Kind: "int", Value: "99"},
+			{Kind: "int", Value: "9007199254740993"},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := tt.key
+			input.IndexValues = toInputDatums(tt.key.IndexValues)
+			input.CommonHandle = toInputDatums(tt.key.CommonHandle)
+
+			encoded, err := EncodeTiDBKey(&input)
+			if err != nil {
+				t.Fatalf("EncodeTiDBKey: %v", err)
+			}
+			got, ok := DecodeTiDBKey(encoded)
+			if !ok {
+				t.Fatalf("DecodeTiDBKey(%x) did not recognise the key", encoded)
+			}
+			if !reflect.DeepEqual(*got, tt.key) {
+				t.Errorf("round trip = %+v, want %+v", *got, tt.key)
+			}
+		})
+	}
+}
+
+// toInputDatums converts decoded datums to what a JSON request would carry: whole numbers as
+// json.Number, even past 2^53.
+func toInputDatums(datums []types.TiDBDatum) []types.TiDBDatum {
+	var out []types.TiDBDatum
+	for _, d := range datums {
+		if v, ok := d.Value.(string); ok && d.Kind != "bytes" {
+			d.Value = json.Number(v)
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+func TestTiDBDatumNumbers(t *testing.T) {
+	var key types.TiDBKey
+	if err := json.Unmarshal([]byte(`{"table_id": 45, "index_id": 1, "index_values": [{"value": 9007199254740993}, {"value": 2.5}]}`), &key); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := EncodeTiDBKey(&key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := DecodeTiDBKey(encoded)
+	want := []types.TiDBDatum{{Kind: "int", Value: "9007199254740993"}, {Kind: "float", Value: 2.5}}
+	if got == nil || !reflect.DeepEqual(got.IndexValues, want) {
+		t.Errorf("index values = %+v, want %+v", got, want)
+	}
+
+	if _, err := EncodeTiDBKey(&types.TiDBKey{TableID: 45, IndexID: int64Ptr(1), IndexValues: []types.TiDBDatum{{Kind: "uint", Value: float64(1 << 60)}}}); err == nil {
+		t.Error("a float64 past 2^53 was accepted as a uint")
+	}
+}
+
+func TestEncodeTiDBKeyPrefixes(t *testing.T) {
+	table, _ := EncodeTiDBKey(&types.TiDBKey{TableID: 45})
+	rows, _ := EncodeTiDBKey(&types.TiDBKey{Kind: types.TiDBKeyRecord, TableID: 45})
+	row, _ := EncodeTiDBKey(&types.TiDBKey{TableID: 45, Handle: int64Ptr(1)})
+	index, _ := EncodeTiDBKey(&types.TiDBKey{TableID: 45, IndexID: int64Ptr(1)})
+	entry, _ := EncodeTiDBKey(&types.TiDBKey{TableID: 45, IndexID: int64Ptr(1), IndexValues: []types.TiDBDatum{{Value: "a"}}})
+
+	if want := []byte{'t', 0x80, 0, 0, 0, 0, 0, 0, 45}; !bytes.Equal(table, want) {
+		t.Errorf("table prefix = %x, want %x", table, want)
+	}
+	for _, k := range [][]byte{rows, row, index, entry} {
+		if !bytes.HasPrefix(k, table) {
+			t.Errorf("%x does not start with the table prefix", k)
+		}
+	}
+	if !bytes.HasPrefix(row, rows) || !bytes.HasPrefix(entry, index) {
+		t.Error("full keys do not start with their prefixes")
+	}
+
+	if _, err := EncodeTiDBKey(&types.TiDBKey{Kind: types.TiDBKeyIndex, TableID: 45}); err == nil {
+		t.Error("expected an error for an index key without index_id")
+	}
+	if _, err := EncodeTiDBKey(&types.TiDBKey{TableID: 45, Handle: int64Ptr(1), CommonHandle: []types.TiDBDatum{{Value: "a"}}}); err == nil {
+		t.Error("expected an error for a key with both handle and common_handle")
+	}
+}
+
+func TestDecodeTiDBKeyEncoded(t *testing.T) {
+	plain, _ := EncodeTiDBKey(&types.TiDBKey{TableID: 45, Handle: int64Ptr(12)})
+	stored := binary.BigEndian.AppendUint64(encodeCmpBytes(nil, plain), ^uint64(4242))
+
+	got, ok := DecodeTiDBKey(stored)
+	if !ok {
+		t.Fatalf("DecodeTiDBKey(%x) did not recognise the key", stored)
+	}
+	if !got.Encoded || got.TS == nil || *got.TS != 4242 {
+		t.Errorf("encoded = %v, ts = %v, want true and 4242", got.Encoded, got.TS)
+	}
+	if got.Handle == nil || *got.Handle != 12 || got.TableID != 45 {
+		t.Errorf("decoded %+v, want table 45 handle 12", *got)
+	}
+}
+
+func TestDecodeTiDBKeyDecimal(t *testing.T) {
+	base, _ := EncodeTiDBKey(&types.TiDBKey{TableID: 1, IndexID: int64Ptr(1)})
+	tests := []struct {
+		bin  []byte
+		want string
+	}{
+		// DECIMAL(2,1): one byte for the integer digit and one for the fraction digit.
+		{bin: []byte{tidbDecimalFlag, 2, 1, 0x81, 0x05}, want: "1.5"},
+		{bin: []byte{tidbDecimalFlag, 2, 1, 0x7e, 0xfa}, want: "-1.5"},
+		// DECIMAL(12,2): 10 integer digits take one leading byte plus a 4-byte word of 9 digits.
+		{bin: []byte{tidbDecimalFlag, 12, 2, 0x81, 0x0d, 0xfb, 0x38, 0xd2, 0x07}, want: "1234567890.07"},
+	}
+
+	for _, tt := range tests {
+		got, ok := DecodeTiDBKey(append(bytes.Clone(base), tt.bin...))
+		if !ok || len(got.IndexValues) != 1 {
+			t.Fatalf("decimal %x was not decoded: %+v", tt.bin, got)
+		}
+		if got.IndexValues[0].Value != tt.want {
+			t.Errorf("decimal %x = %v, want %s", tt.bin, got.IndexValues[0].Value, tt.want)
+		}
+	}
+}
+
+func TestDecodeTiDBKeyRejectsOtherKeys(t *testing.T) {
+	table, _ := EncodeTiDBKey(&types.TiDBKey{TableID: 45})
+	for _, key := range [][]byte{[]byte("feed:123"), []byte("tenant:42"), []byte("tenant:42_r12345678"), table, nil} {
+		if k, ok := DecodeTiDBKey(key); ok {
+			t.Errorf("DecodeTiDBKey(%q) = %+v, want no match", key, *k)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenStore issues short-lived, single-use tokens bound to an opaque subject string.
+type TokenStore struct {
+	mu     sync.Mutex
+	ttl    time.Duration
+	tokens map[string]tokenEntry
+}
+
+type tokenEntry struct {
+	subject string
+	expires time.Time
+}
+
+func NewTokenStore(ttl time.Duration) *TokenStore {
+	return &TokenStore{
+		ttl:    ttl,
+		tokens: make(map[string]tokenEntry),
+	}
+}
+
+// Issue creates a token for subject and returns it together with its expiry time.
+func (t *TokenStore) Issue(subject string) (string, time.Time, error) {
+	token, err := RandHex(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for k, e := range t.tokens {
+		if now.After(e.expires) {
+			delete(t.tokens, k)
+		}
+	}
+	expires := now.Add(t.ttl)
+	t.tokens[token] = tokenEntry{subject: subject, expires: expires}
+	return token, expires, nil
+}
+
+// Consume reports whether token is valid for subject. A token can only be consumed once.
+func (t *TokenStore) Consume(token, subject string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.tokens[token]
+	if !ok {
+		return false
+	}
+	delete(t.tokens, token)
+	return e.subject == subject && time.Now().Before(e.expires)
+}